@@ -0,0 +1,331 @@
+package alert
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/solvewer/server-monitor/configuration"
+	"github.com/solvewer/server-monitor/monitor"
+	"go.uber.org/zap"
+)
+
+type State string
+
+const (
+	StateInactive State = "inactive"
+	StatePending  State = "pending"
+	StateFiring   State = "firing"
+	StateResolved State = "resolved"
+)
+
+const (
+	// notifyTimeout 单次通知（含重试）的超时时间
+	notifyTimeout = 2 * time.Minute
+	// dispatchQueue 待发送通知的队列长度
+	dispatchQueue = 64
+
+	// staleCycles 序列连续缺失的上报周期数，超过后判定为停止上报
+	staleCycles = 3
+	// staleUnknown 只上报过一次、无法得知上报间隔的序列，缺失超过该时间判定为停止上报；
+	// 需大于最长的采集间隔（证书、表空间为1小时）
+	staleUnknown = 2 * time.Hour
+)
+
+// Event 告警状态变化事件
+type Event struct {
+	Rule      string            `json:"rule"`
+	Severity  string            `json:"severity"`
+	Summary   string            `json:"summary,omitempty"`
+	State     State             `json:"state"`
+	Source    string            `json:"source"`
+	Node      int               `json:"node"`
+	Metric    string            `json:"metric"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Value     float64           `json:"value"`
+	Threshold float64           `json:"threshold"`
+	Since     time.Time         `json:"since"`           // 告警开始时间
+	At        time.Time         `json:"at"`              // 事件发生时间
+	Stale     bool              `json:"stale,omitempty"` // 因序列停止上报（目标删除、采集失败）而恢复
+}
+
+// Notifier 告警通知渠道
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, events []Event) error
+}
+
+// CycleNotifier 需要感知每个统计周期的通知渠道（如邮件摘要），
+// 每个周期都会收到当前仍在触发的告警，由渠道自行决定何时发送
+type CycleNotifier interface {
+	Notifier
+	Cycle(ctx context.Context, t time.Time, firing []Event) error
+}
+
+// series 单条时间序列在某条规则下的状态
+type series struct {
+	state      State
+	since      time.Time
+	clearSince time.Time
+	seen       time.Time     // 最近一次上报的统计时间
+	interval   time.Duration // 最近两次上报的间隔
+	event      Event
+}
+
+// stale 序列是否已停止上报
+func (s *series) stale(t time.Time) bool {
+	after := staleUnknown
+	if s.interval > 0 {
+		after = staleCycles * s.interval
+	}
+	return t.Sub(s.seen) > after
+}
+
+// dispatchJob 一个统计周期待发送的通知
+type dispatchJob struct {
+	t      time.Time
+	events []Event
+	firing []Event
+}
+
+// Engine 告警规则引擎，作为 monitor.Sink 在每个统计周期结束后评估规则
+type Engine struct {
+	mu        sync.Mutex
+	rules     []*Rule
+	series    map[string]*series
+	notifiers []Notifier
+	jobs      chan dispatchJob
+	logger    *zap.Logger
+}
+
+func NewEngine(cfg configuration.AlertsConfig, logger *zap.Logger) (*Engine, error) {
+	e := &Engine{
+		series: make(map[string]*series),
+		jobs:   make(chan dispatchJob, dispatchQueue),
+		logger: logger,
+	}
+	for _, ruleCfg := range cfg.Rules {
+		rule, err := ParseRule(ruleCfg)
+		if err != nil {
+			return nil, err
+		}
+		e.rules = append(e.rules, rule)
+	}
+	go e.worker()
+	return e, nil
+}
+
+func (e *Engine) AddNotifier(n Notifier) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.notifiers = append(e.notifiers, n)
+}
+
+// Rules 返回规则数量
+func (e *Engine) Rules() int {
+	return len(e.rules)
+}
+
+func (e *Engine) Name() string {
+	return "alert"
+}
+
+func (e *Engine) Write(ctx context.Context, snapshot *monitor.Snapshot) error {
+	events := e.Evaluate(snapshot.Source, snapshot.Node, snapshot.Time, snapshot.Samples)
+	// 通知可能包含重试，由单个后台协程按周期顺序发送，避免阻塞统计周期且保证触发与恢复的先后
+	select {
+	case e.jobs <- dispatchJob{t: snapshot.Time, events: events, firing: e.Firing()}:
+		return nil
+	case <-ctx.Done():
+		e.logger.Error("告警通知队列已满，丢弃本周期通知", zap.Int("events", len(events)))
+		return ctx.Err()
+	}
+}
+
+func (e *Engine) worker() {
+	for job := range e.jobs {
+		e.dispatch(job.t, job.events, job.firing)
+	}
+}
+
+// Evaluate 评估本周期样本，返回触发与恢复事件
+func (e *Engine) Evaluate(source string, node int, t time.Time, samples []monitor.Sample) []Event {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var events []Event
+	for _, rule := range e.rules {
+		threshold, recoverAt, duration, enabled := rule.forNode(node)
+		if !enabled {
+			continue
+		}
+
+		for _, sample := range samples {
+			if !rule.matches(sample.Name, sample.Labels) {
+				continue
+			}
+
+			key := rule.Name + "|" + source + "|" + seriesKey(node, sample.Labels)
+			s, ok := e.series[key]
+			if !ok {
+				s = &series{state: StateInactive}
+				e.series[key] = s
+			}
+			if !s.seen.IsZero() && t.After(s.seen) {
+				s.interval = t.Sub(s.seen)
+			}
+			s.seen = t
+			s.event = Event{
+				Rule:      rule.Name,
+				Severity:  rule.Severity,
+				Summary:   rule.Summary,
+				Source:    source,
+				Node:      node,
+				Metric:    sample.Name,
+				Labels:    sample.Labels,
+				Value:     sample.Value,
+				Threshold: threshold,
+				At:        t,
+			}
+
+			if event, changed := e.transition(s, rule, t, sample.Value, threshold, recoverAt, duration); changed {
+				events = append(events, event)
+			}
+		}
+	}
+	return append(events, e.expire(source, node, t)...)
+}
+
+// expire 清理同一来源中已停止上报的序列，仍在触发的告警以恢复事件结束，
+// 避免目标删除、标签变化或采集失败后告警永远处于触发状态
+func (e *Engine) expire(source string, node int, t time.Time) []Event {
+	var events []Event
+	for key, s := range e.series {
+		if s.event.Source != source || s.event.Node != node || !s.stale(t) {
+			continue
+		}
+		if s.state == StateFiring {
+			event := s.emit(StateResolved)
+			event.At = t
+			event.Stale = true
+			events = append(events, event)
+		}
+		delete(e.series, key)
+	}
+	return events
+}
+
+// transition 推进单条序列的状态机：inactive -> pending -> firing -> resolved
+func (e *Engine) transition(s *series, rule *Rule, t time.Time, value, threshold, recoverAt float64, duration time.Duration) (Event, bool) {
+	switch s.state {
+	case StateInactive, StateResolved:
+		if !compare(rule.Op, value, threshold) {
+			return Event{}, false
+		}
+		s.since = t
+		s.state = StatePending
+		e.logger.Info("告警待定", zap.String("rule", rule.Name), zap.Int("node", s.event.Node), zap.Float64("value", value))
+		fallthrough
+
+	case StatePending:
+		if !compare(rule.Op, value, threshold) {
+			s.state = StateInactive
+			return Event{}, false
+		}
+		if t.Sub(s.since) < duration {
+			return Event{}, false
+		}
+		s.state = StateFiring
+		s.clearSince = time.Time{}
+		return s.emit(StateFiring), true
+
+	case StateFiring:
+		// 防抖：达到恢复阈值并持续 ResolveFor 才判定恢复
+		if compare(rule.Op, value, recoverAt) {
+			s.clearSince = time.Time{}
+			return Event{}, false
+		}
+		if s.clearSince.IsZero() {
+			s.clearSince = t
+		}
+		if t.Sub(s.clearSince) < rule.ResolveFor {
+			return Event{}, false
+		}
+		s.state = StateResolved
+		return s.emit(StateResolved), true
+	}
+	return Event{}, false
+}
+
+func (s *series) emit(state State) Event {
+	event := s.event
+	event.State = state
+	event.Since = s.since
+	return event
+}
+
+// Firing 返回当前处于触发状态的告警
+func (e *Engine) Firing() []Event {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var events []Event
+	for _, s := range e.series {
+		if s.state == StateFiring {
+			events = append(events, s.emit(StateFiring))
+		}
+	}
+	return events
+}
+
+func (e *Engine) dispatch(t time.Time, events []Event, firing []Event) {
+	for _, event := range events {
+		if event.State == StateFiring {
+			e.logger.Warn("告警触发", eventFields(event)...)
+		} else if event.Stale {
+			e.logger.Info("告警恢复（序列已停止上报）", eventFields(event)...)
+		} else {
+			e.logger.Info("告警恢复", eventFields(event)...)
+		}
+	}
+
+	e.mu.Lock()
+	notifiers := append([]Notifier(nil), e.notifiers...)
+	e.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, n := range notifiers {
+		wg.Add(1)
+		go func(n Notifier) {
+			defer wg.Done()
+			if len(events) > 0 {
+				if err := n.Notify(ctx, events); err != nil {
+					e.logger.Error("告警通知发送失败", zap.String("notifier", n.Name()), zap.Error(err))
+				}
+			}
+			if cn, ok := n.(CycleNotifier); ok {
+				if err := cn.Cycle(ctx, t, firing); err != nil {
+					e.logger.Error("告警通知发送失败", zap.String("notifier", n.Name()), zap.Error(err))
+				}
+			}
+		}(n)
+	}
+	wg.Wait()
+}
+
+func eventFields(event Event) []zap.Field {
+	return []zap.Field{
+		zap.String("rule", event.Rule),
+		zap.String("severity", event.Severity),
+		zap.String("source", event.Source),
+		zap.Int("node", event.Node),
+		zap.String("metric", event.Metric),
+		zap.Any("labels", event.Labels),
+		zap.Float64("value", event.Value),
+		zap.Float64("threshold", event.Threshold),
+		zap.Time("since", event.Since),
+	}
+}
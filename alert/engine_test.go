@@ -0,0 +1,139 @@
+package alert
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/solvewer/server-monitor/configuration"
+	"github.com/solvewer/server-monitor/monitor"
+	"go.uber.org/zap"
+)
+
+func newTestEngine(t *testing.T, rules ...configuration.RuleConfig) *Engine {
+	t.Helper()
+	e, err := NewEngine(configuration.AlertsConfig{Rules: rules}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func states(events []Event) []State {
+	list := make([]State, 0, len(events))
+	for _, event := range events {
+		list = append(list, event.State)
+	}
+	return list
+}
+
+func TestEngineTransitions(t *testing.T) {
+	e := newTestEngine(t, configuration.RuleConfig{Expr: "cpu_usage > 90 for 2m", Recover: float(80)})
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+
+	steps := []struct {
+		value float64
+		want  []State
+	}{
+		{95, nil},                    // pending
+		{95, nil},                    // 持续1分钟
+		{95, []State{StateFiring}},   // 持续2分钟，触发
+		{85, nil},                    // 未回落到恢复阈值以下
+		{79, []State{StateResolved}}, // 恢复
+		{50, nil},                    // 保持恢复
+		{91, nil},                    // 重新 pending
+		{50, nil},                    // pending 期间回落，不触发
+	}
+	for i, step := range steps {
+		at := start.Add(time.Duration(i) * time.Minute)
+		events := e.Evaluate("web", 1, at, []monitor.Sample{{Name: "cpu_usage", Value: step.value}})
+		if got := states(events); len(got) != len(step.want) || (len(got) > 0 && got[0] != step.want[0]) {
+			t.Fatalf("step %d value %v: events %v, want %v", i, step.value, got, step.want)
+		}
+	}
+}
+
+func TestEngineStaleSeriesResolved(t *testing.T) {
+	e := newTestEngine(t, configuration.RuleConfig{Expr: "ping_loss > 50"})
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	down := []monitor.Sample{{Name: "ping_loss", Value: 100, Labels: map[string]string{"target": "a"}}}
+	other := []monitor.Sample{{Name: "ping_loss", Value: 0, Labels: map[string]string{"target": "b"}}}
+
+	e.Evaluate("web", 1, start, append(down, other...))
+	e.Evaluate("web", 1, start.Add(time.Minute), append(down, other...))
+	if len(e.Firing()) != 1 {
+		t.Fatalf("firing = %v", e.Firing())
+	}
+
+	// 目标 a 从配置中删除，之后不再上报
+	var resolved []Event
+	for i := 2; i <= 6; i++ {
+		resolved = append(resolved, e.Evaluate("web", 1, start.Add(time.Duration(i)*time.Minute), other)...)
+	}
+	if len(resolved) != 1 || resolved[0].State != StateResolved || !resolved[0].Stale || resolved[0].Labels["target"] != "a" {
+		t.Fatalf("resolved = %+v", resolved)
+	}
+	if len(e.Firing()) != 0 {
+		t.Fatalf("firing after stale = %v", e.Firing())
+	}
+	if len(e.series) != 1 {
+		t.Fatalf("series not pruned: %d", len(e.series))
+	}
+
+	// 其他来源的快照不会清理本来源的序列
+	e.Evaluate("mysql", 1, start.Add(time.Hour), nil)
+	if len(e.series) != 1 {
+		t.Fatalf("series pruned by another source: %d", len(e.series))
+	}
+}
+
+// recordNotifier 记录收到的事件，第一次发送时阻塞一段时间以模拟重试
+type recordNotifier struct {
+	mu     sync.Mutex
+	events []Event
+	done   chan struct{}
+}
+
+func (*recordNotifier) Name() string { return "record" }
+
+func (n *recordNotifier) Notify(_ context.Context, events []Event) error {
+	n.mu.Lock()
+	first := len(n.events) == 0
+	n.mu.Unlock()
+	if first {
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.events = append(n.events, events...)
+	if len(n.events) == 2 {
+		close(n.done)
+	}
+	return nil
+}
+
+func TestEngineDispatchInOrder(t *testing.T) {
+	e := newTestEngine(t, configuration.RuleConfig{Expr: "cpu_usage > 90"})
+	n := &recordNotifier{done: make(chan struct{})}
+	e.AddNotifier(n)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	for i, value := range []float64{95, 50} {
+		snapshot := &monitor.Snapshot{Source: "web", Node: 1, Time: start.Add(time.Duration(i) * time.Minute),
+			Samples: []monitor.Sample{{Name: "cpu_usage", Value: value}}}
+		if err := e.Write(context.Background(), snapshot); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	select {
+	case <-n.done:
+	case <-time.After(time.Second):
+		t.Fatal("notifications not delivered")
+	}
+	if got := states(n.events); got[0] != StateFiring || got[1] != StateResolved {
+		t.Fatalf("events out of order: %v", got)
+	}
+}
@@ -0,0 +1,151 @@
+package alert
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/solvewer/server-monitor/configuration"
+)
+
+// Rule 解析后的告警规则，表达式形如：disk_usage{mountpoint="/data"} > 90 for 5m
+type Rule struct {
+	Name       string
+	Severity   string
+	Summary    string
+	Metric     string
+	Matchers   map[string]string
+	Op         string
+	Threshold  float64
+	For        time.Duration
+	Recover    *float64      // 恢复阈值，为空时与 Threshold 相同；用于防抖
+	ResolveFor time.Duration // 条件解除持续多久才判定恢复
+	Nodes      map[int]configuration.NodeOverride
+}
+
+var exprPattern = regexp.MustCompile(`^\s*([a-zA-Z_:][a-zA-Z0-9_:]*)\s*(\{[^}]*\})?\s*(>=|<=|==|!=|>|<)\s*(-?[0-9.eE+-]+)\s*(?:for\s+(\S+))?\s*$`)
+
+var matcherPattern = regexp.MustCompile(`^\s*([a-zA-Z_][a-zA-Z0-9_]*)\s*=\s*"([^"]*)"\s*$`)
+
+// ParseRule 解析配置中的告警规则
+func ParseRule(cfg configuration.RuleConfig) (*Rule, error) {
+	m := exprPattern.FindStringSubmatch(cfg.Expr)
+	if m == nil {
+		return nil, fmt.Errorf("告警规则 %s 表达式无效: %q", cfg.Name, cfg.Expr)
+	}
+
+	rule := &Rule{
+		Name:       cfg.Name,
+		Severity:   cfg.Severity,
+		Summary:    cfg.Summary,
+		Metric:     m[1],
+		Matchers:   make(map[string]string),
+		Op:         m[3],
+		Recover:    cfg.Recover,
+		ResolveFor: cfg.ResolveFor,
+		Nodes:      cfg.Nodes,
+	}
+	if rule.Name == "" {
+		rule.Name = rule.Metric
+	}
+	if rule.Severity == "" {
+		rule.Severity = "warning"
+	}
+
+	var err error
+	if rule.Threshold, err = strconv.ParseFloat(m[4], 64); err != nil {
+		return nil, fmt.Errorf("告警规则 %s 阈值无效: %w", rule.Name, err)
+	}
+	if m[5] != "" {
+		if rule.For, err = time.ParseDuration(m[5]); err != nil {
+			return nil, fmt.Errorf("告警规则 %s 持续时间无效: %w", rule.Name, err)
+		}
+	}
+
+	if m[2] != "" {
+		for _, part := range strings.Split(strings.Trim(m[2], "{}"), ",") {
+			if strings.TrimSpace(part) == "" {
+				continue
+			}
+			kv := matcherPattern.FindStringSubmatch(part)
+			if kv == nil {
+				return nil, fmt.Errorf("告警规则 %s 标签匹配无效: %q", rule.Name, part)
+			}
+			rule.Matchers[kv[1]] = kv[2]
+		}
+	}
+
+	return rule, nil
+}
+
+// forNode 应用节点级覆盖，返回阈值、持续时间以及是否启用
+func (r *Rule) forNode(node int) (threshold float64, recoverAt float64, duration time.Duration, enabled bool) {
+	threshold, duration, enabled = r.Threshold, r.For, true
+	override, ok := r.Nodes[node]
+	if ok {
+		if override.Threshold != nil {
+			threshold = *override.Threshold
+		}
+		if override.For != nil {
+			duration = *override.For
+		}
+		enabled = !override.Disabled
+	}
+
+	recoverAt = threshold
+	if r.Recover != nil {
+		// 恢复阈值与告警阈值保持相同的差值，节点覆盖阈值时一同平移
+		recoverAt = threshold + (*r.Recover - r.Threshold)
+	}
+	return
+}
+
+// matches 判断样本是否属于该规则
+func (r *Rule) matches(name string, labels map[string]string) bool {
+	if name != r.Metric {
+		return false
+	}
+	for k, v := range r.Matchers {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func compare(op string, value, threshold float64) bool {
+	switch op {
+	case ">":
+		return value > threshold
+	case ">=":
+		return value >= threshold
+	case "<":
+		return value < threshold
+	case "<=":
+		return value <= threshold
+	case "==":
+		return value == threshold
+	case "!=":
+		return value != threshold
+	}
+	return false
+}
+
+// seriesKey 由节点与标签生成序列标识
+func seriesKey(node int, labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(strconv.Itoa(node))
+	for _, k := range keys {
+		b.WriteString("," + k + "=" + labels[k])
+	}
+	return b.String()
+}
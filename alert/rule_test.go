@@ -0,0 +1,114 @@
+package alert
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/solvewer/server-monitor/configuration"
+)
+
+func float(v float64) *float64 { return &v }
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		expr      string
+		metric    string
+		matchers  map[string]string
+		op        string
+		threshold float64
+		duration  time.Duration
+	}{
+		{"cpu_usage > 90", "cpu_usage", map[string]string{}, ">", 90, 0},
+		{"disk_usage{mountpoint=\"/data\"} >= 85.5 for 5m", "disk_usage", map[string]string{"mountpoint": "/data"}, ">=", 85.5, 5 * time.Minute},
+		{"  ping_loss{target=\"a\", } != 0 for 30s ", "ping_loss", map[string]string{"target": "a"}, "!=", 0, 30 * time.Second},
+		{"dns_up{name=\"x\",resolver=\"1.1.1.1:53\"}<1", "dns_up", map[string]string{"name": "x", "resolver": "1.1.1.1:53"}, "<", 1, 0},
+		{"datadir_growth_rate > -1e3", "datadir_growth_rate", map[string]string{}, ">", -1000, 0},
+	}
+	for _, tt := range tests {
+		rule, err := ParseRule(configuration.RuleConfig{Expr: tt.expr})
+		if err != nil {
+			t.Errorf("ParseRule(%q): %v", tt.expr, err)
+			continue
+		}
+		if rule.Metric != tt.metric || rule.Op != tt.op || rule.Threshold != tt.threshold || rule.For != tt.duration ||
+			!reflect.DeepEqual(rule.Matchers, tt.matchers) {
+			t.Errorf("ParseRule(%q) = %+v", tt.expr, rule)
+		}
+		if rule.Name != tt.metric || rule.Severity != "warning" {
+			t.Errorf("ParseRule(%q) defaults: name=%q severity=%q", tt.expr, rule.Name, rule.Severity)
+		}
+	}
+}
+
+func TestParseRuleInvalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"cpu_usage",
+		"cpu_usage >",
+		"cpu_usage => 90",
+		"cpu_usage > abc",
+		"cpu_usage > 90 for 5",
+		"disk_usage{mountpoint=/data} > 90",
+		"1cpu > 90",
+	} {
+		if _, err := ParseRule(configuration.RuleConfig{Name: "r", Expr: expr}); err == nil {
+			t.Errorf("ParseRule(%q) expected error", expr)
+		}
+	}
+}
+
+func TestRuleForNode(t *testing.T) {
+	fiveMinutes := 5 * time.Minute
+	rule, err := ParseRule(configuration.RuleConfig{
+		Expr:    "cpu_usage > 90 for 1m",
+		Recover: float(85),
+		Nodes: map[int]configuration.NodeOverride{
+			2: {Threshold: float(95), For: &fiveMinutes},
+			3: {Disabled: true},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		node      int
+		threshold float64
+		recoverAt float64
+		duration  time.Duration
+		enabled   bool
+	}{
+		{1, 90, 85, time.Minute, true},
+		{2, 95, 90, fiveMinutes, true}, // 恢复阈值随节点阈值平移
+		{3, 90, 85, time.Minute, false},
+	}
+	for _, tt := range tests {
+		threshold, recoverAt, duration, enabled := rule.forNode(tt.node)
+		if threshold != tt.threshold || recoverAt != tt.recoverAt || duration != tt.duration || enabled != tt.enabled {
+			t.Errorf("forNode(%d) = %v, %v, %v, %v", tt.node, threshold, recoverAt, duration, enabled)
+		}
+	}
+}
+
+func TestRuleMatches(t *testing.T) {
+	rule, err := ParseRule(configuration.RuleConfig{Expr: `disk_usage{mountpoint="/data"} > 90`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		labels map[string]string
+		want   bool
+	}{
+		{"disk_usage", map[string]string{"mountpoint": "/data", "device": "sda1"}, true},
+		{"disk_usage", map[string]string{"mountpoint": "/"}, false},
+		{"disk_usage", nil, false},
+		{"disk_inode_usage", map[string]string{"mountpoint": "/data"}, false},
+	}
+	for _, tt := range tests {
+		if got := rule.matches(tt.name, tt.labels); got != tt.want {
+			t.Errorf("matches(%s, %v) = %v, want %v", tt.name, tt.labels, got, tt.want)
+		}
+	}
+}
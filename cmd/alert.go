@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"github.com/solvewer/server-monitor/alert"
+	"github.com/solvewer/server-monitor/configuration"
+	"github.com/solvewer/server-monitor/monitor"
+	"github.com/solvewer/server-monitor/notify"
+	"go.uber.org/zap"
+)
+
+// setupAlerts 根据配置创建告警引擎，并作为快照接收者挂到监控流程上
+func setupAlerts(logName string) error {
+	cfg := configuration.GetConfig()
+	if len(cfg.Alerts.Rules) == 0 {
+		return nil
+	}
+
+	logger := configuration.GetLogger(logName)
+	engine, err := alert.NewEngine(cfg.Alerts, logger)
+	if err != nil {
+		logger.Error("初始化告警规则失败", zap.Error(err))
+		return err
+	}
+
+	for _, notifierCfg := range cfg.Notifiers {
+		notifier, err := notify.New(notifierCfg, logger)
+		if err != nil {
+			logger.Error("初始化告警通知失败", zap.String("notifier", notifierCfg.Name), zap.Error(err))
+			return err
+		}
+		engine.AddNotifier(notifier)
+	}
+
+	monitor.AddSink(engine)
+	logger.Info("告警规则已加载", zap.Int("rules", engine.Rules()), zap.Int("notifiers", len(cfg.Notifiers)))
+	return nil
+}
@@ -14,8 +14,9 @@ import (
 )
 
 var (
-	file    string
-	rootCmd = &cobra.Command{
+	file          string
+	metricsListen string
+	rootCmd       = &cobra.Command{
 		Use: "app",
 		Run: func(cmd *cobra.Command, args []string) {
 			fmt.Println("Starting application...")
@@ -51,6 +52,7 @@ func Exec() {
 		} else {
 			configuration.GetLogger(configuration.GlobalLogName).Error("程序执行发生错误：", zap.Error(err))
 		}
+		os.Exit(1)
 	} else {
 		configuration.GetLogger(configuration.GlobalLogName).Info("程序执行成功", zap.String("监控项", cmd.Name()))
 	}
@@ -78,12 +80,18 @@ func Exec() {
 func startMonitor() *cobra.Command {
 	monitorCmd := &cobra.Command{
 		Use: configuration.WebLogName,
-		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// 参数解析完成后再加载 -f 指定的配置文件
+			if err := configuration.Load(file); err != nil {
+				return err
+			}
 			// web服务器监控日志
-			configuration.InitLogger(configuration.WebLogName, util.LogPath(configuration.WebLogName))
+			configuration.InitLogger(configuration.WebLogName, util.LogPath(configuration.GetConfig().Log.Dir, configuration.WebLogName))
+			return setupAlerts(configuration.WebLogName)
 		},
 		Run: func(cmd *cobra.Command, args []string) {
 			configuration.GetLogger(configuration.WebLogName).Info("开始监控服务器", zap.String("配置文件", file))
+			applyFlags(cmd)
 
 			// 开启监控
 			monitor.Start()
@@ -98,12 +106,18 @@ func startMonitor() *cobra.Command {
 func mysqlMonitor() *cobra.Command {
 	mysqlCmd := &cobra.Command{
 		Use: configuration.MysqlLogName,
-		PersistentPreRun: func(cmd *cobra.Command, args []string) {
-			// web服务器监控日志
-			configuration.InitLogger(configuration.MysqlLogName, util.LogPath(configuration.MysqlLogName))
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// 参数解析完成后再加载 -f 指定的配置文件
+			if err := configuration.Load(file); err != nil {
+				return err
+			}
+			// mysql监控日志
+			configuration.InitLogger(configuration.MysqlLogName, util.LogPath(configuration.GetConfig().Log.Dir, configuration.MysqlLogName))
+			return setupAlerts(configuration.MysqlLogName)
 		},
 		Run: func(cmd *cobra.Command, args []string) {
 			configuration.GetLogger(configuration.MysqlLogName).Info("开始监控Mysql数据库", zap.String("配置文件", file))
+			applyFlags(cmd)
 
 			monitor.StartMysql()
 		},
@@ -115,6 +129,14 @@ func mysqlMonitor() *cobra.Command {
 }
 
 func validateArgs(cmd *cobra.Command) {
-	cmd.Flags().StringVarP(&file, "file", "f", ".env", "The file to run the server-monitor")
+	cmd.Flags().StringVarP(&file, "file", "f", ".env", "The config file to run the server-monitor (env, yaml or toml)")
 	_ = cmd.MarkFlagRequired("file")
+	cmd.Flags().StringVar(&metricsListen, "metrics-listen", "", "Prometheus /metrics listen address, overrides metrics.listen in the config file")
+}
+
+// applyFlags 命令行参数覆盖配置文件
+func applyFlags(cmd *cobra.Command) {
+	if cmd.Flags().Changed("metrics-listen") {
+		configuration.GetConfig().Metrics.Listen = metricsListen
+	}
 }
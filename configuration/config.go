@@ -8,16 +8,260 @@ import (
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/schema"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
-	DbHost     string
-	DbUsername string
-	DbPassword string
-	DbName     string
-	DbPort     int
-	WebNode    int
+	DB         DBConfig         `mapstructure:"db"`
+	Node       int              `mapstructure:"node"`     // 服务器节点标志 0 WEB服务器 1-3 分别代表3台ES服务器
+	Interval   time.Duration    `mapstructure:"interval"` // 统计周期，默认1分钟
+	Collectors CollectorsConfig `mapstructure:"collectors"`
+	Mysql      MysqlConfig      `mapstructure:"mysql"`
+	Sinks      SinksConfig      `mapstructure:"sinks"`
+	Metrics    MetricsConfig    `mapstructure:"metrics"`
+	Alerts     AlertsConfig     `mapstructure:"alerts"`
+	Notifiers  []NotifierConfig `mapstructure:"notifiers"`
+	Log        LogConfig        `mapstructure:"log"`
+}
+
+// MysqlConfig mysql-monitor 监控的实例，未配置时监控存储库本身
+type MysqlConfig struct {
+	Instances   []MysqlInstance   `mapstructure:"instances"`
+	Processlist ProcesslistConfig `mapstructure:"processlist"`
+	Digest      DigestConfig      `mapstructure:"digest"`
+	Tables      TablesConfig      `mapstructure:"tables"`
+	Status      StatusConfig      `mapstructure:"status"`
+}
+
+// StatusConfig 从 SHOW GLOBAL STATUS/VARIABLES 记录的指标，支持 glob 模式，如 Com_*
+type StatusConfig struct {
+	Rates     []string `mapstructure:"rates"`     // 累计计数，记录每秒速率
+	Gauges    []string `mapstructure:"gauges"`    // 状态变量当前值
+	Variables []string `mapstructure:"variables"` // 系统变量当前值，ON/OFF 记为 1/0
+}
+
+// TablesConfig 表空间增长跟踪
+type TablesConfig struct {
+	MinSize int `mapstructure:"min_size"` // 只保存不小于该大小的表，MB；库汇总与容量预测不受影响
+}
+
+// DigestConfig performance_schema 语句摘要 Top N
+type DigestConfig struct {
+	TopN       int    `mapstructure:"top_n"`       // 每个实例每周期保存的条数，默认20
+	OrderBy    string `mapstructure:"order_by"`    // latency / count / rows_examined，默认 latency
+	TextLength int    `mapstructure:"text_length"` // 摘要文本截断长度，默认1024
+}
+
+// ProcesslistConfig 长查询快照与自动终止策略
+type ProcesslistConfig struct {
+	Threshold time.Duration `mapstructure:"threshold"`  // 执行时间超过该值的语句记录快照，默认10秒
+	MaxRows   int           `mapstructure:"max_rows"`   // 每个实例每周期最多记录的语句数，默认50
+	SQLLength int           `mapstructure:"sql_length"` // SQL 文本截断长度，默认1024
+	Kill      []KillRule    `mapstructure:"kill"`
+}
+
+// KillRule 自动终止规则，user/db/match 均满足且执行时间超过 after 时执行 KILL QUERY；
+// after 可小于 threshold，此时未达到阈值的语句只用于匹配规则，不记录快照
+type KillRule struct {
+	Name      string        `mapstructure:"name"`
+	Instances []string      `mapstructure:"instances"` // 生效的实例名，为空时对所有实例生效
+	User      string        `mapstructure:"user"`
+	DB        string        `mapstructure:"db"`
+	Match     string        `mapstructure:"match"`   // 匹配原始 SQL 的正则
+	After     time.Duration `mapstructure:"after"`   // 必填，大于 0
+	DryRun    bool          `mapstructure:"dry_run"` // 只记录日志，不实际终止
+}
+
+type MysqlInstance struct {
+	Name  string            `mapstructure:"name"`
+	DSN   string            `mapstructure:"dsn"`   // 如 monitor:xxx@tcp(10.0.0.11:3306)/
+	Tags  map[string]string `mapstructure:"tags"`  // 附加到该实例所有样本的标签
+	Local bool              `mapstructure:"local"` // 是否为本机实例，本机实例记录磁盘IO；回环地址与 unix socket 自动识别
+}
+
+type DBConfig struct {
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	Name     string `mapstructure:"name"`
+}
+
+type CollectorsConfig struct {
+	Enabled   []string                 `mapstructure:"enabled"`   // 启用的采集器，为空表示全部启用
+	Disabled  []string                 `mapstructure:"disabled"`  // 禁用的采集器
+	Intervals map[string]time.Duration `mapstructure:"intervals"` // 按采集器名覆盖采集间隔
+
+	Partitions PartitionsConfig `mapstructure:"partitions"`
+	Interfaces InterfacesConfig `mapstructure:"interfaces"`
+	Devices    DevicesConfig    `mapstructure:"devices"`
+	Ping       PingConfig       `mapstructure:"ping"`
+	Probes     []ProbeConfig    `mapstructure:"probes"`
+	Certs      []CertConfig     `mapstructure:"certs"`
+	DNS        DNSConfig        `mapstructure:"dns"`
+}
+
+// DNSConfig DNS 解析探测，每个查询对每个解析服务器各执行一次
+type DNSConfig struct {
+	Resolvers []string         `mapstructure:"resolvers"` // host 或 host:port，为空时读取 /etc/resolv.conf
+	Queries   []DNSQueryConfig `mapstructure:"queries"`
+	Timeout   time.Duration    `mapstructure:"timeout"` // 单次查询超时，默认5秒
+}
+
+type DNSQueryConfig struct {
+	Name      string   `mapstructure:"name"`
+	Type      string   `mapstructure:"type"`      // A / AAAA / CNAME / MX，默认 A
+	Expect    []string `mapstructure:"expect"`    // 期望的应答集合，为空时只要求有应答
+	Resolvers []string `mapstructure:"resolvers"` // 覆盖全局解析服务器
+}
+
+// CertConfig TLS 证书检查，address 与 file 二选一
+type CertConfig struct {
+	Name       string        `mapstructure:"name"`
+	Address    string        `mapstructure:"address"`     // host:port
+	ServerName string        `mapstructure:"server_name"` // SNI，默认取 address 中的主机名
+	File       string        `mapstructure:"file"`        // 本地 PEM 证书文件
+	Timeout    time.Duration `mapstructure:"timeout"`
+}
+
+// ProbeConfig TCP 端口或 HTTP(S) 探测
+type ProbeConfig struct {
+	Name               string            `mapstructure:"name"`
+	Type               string            `mapstructure:"type"`    // tcp / http，默认 http
+	Address            string            `mapstructure:"address"` // tcp：host:port
+	URL                string            `mapstructure:"url"`     // http：完整地址
+	Method             string            `mapstructure:"method"`  // 默认 GET
+	Headers            map[string]string `mapstructure:"headers"`
+	Body               string            `mapstructure:"body"`
+	ExpectStatus       []int             `mapstructure:"expect_status"` // 期望的状态码，默认 200
+	BodyRegex          string            `mapstructure:"body_regex"`    // 响应体需匹配的正则
+	FollowRedirects    bool              `mapstructure:"follow_redirects"`
+	InsecureSkipVerify bool              `mapstructure:"insecure_skip_verify"`
+	Timeout            time.Duration     `mapstructure:"timeout"` // 默认10秒
+}
+
+// PingConfig ICMP 探测
+type PingConfig struct {
+	Targets    []PingTarget  `mapstructure:"targets"`    // 为空时探测 8.8.8.8
+	Privileged bool          `mapstructure:"privileged"` // true 使用原始 socket（需 root），false 使用 UDP 模式（需 net.ipv4.ping_group_range 允许）
+	Count      int           `mapstructure:"count"`      // 每个目标每周期发送的包数
+	Interval   time.Duration `mapstructure:"interval"`   // 发包间隔
+	Timeout    time.Duration `mapstructure:"timeout"`    // 单个目标最长探测时间
+}
+
+type PingTarget struct {
+	Name string `mapstructure:"name"`
+	Host string `mapstructure:"host"`
+}
+
+// DevicesConfig 块设备过滤，支持 glob 模式，如 loop*
+type DevicesConfig struct {
+	Include []string `mapstructure:"include"`
+	Exclude []string `mapstructure:"exclude"` // 未配置时排除 loop*、ram*、sr* 等
+}
+
+// InterfacesConfig 网卡过滤，支持 glob 模式，如 docker*
+type InterfacesConfig struct {
+	Include []string `mapstructure:"include"`
+	Exclude []string `mapstructure:"exclude"` // 未配置时排除 lo、docker*、veth* 等
+}
+
+// PartitionsConfig 挂载点过滤，挂载点支持 glob 模式，如 /run/*
+type PartitionsConfig struct {
+	IncludeFstypes     []string `mapstructure:"include_fstypes"`
+	ExcludeFstypes     []string `mapstructure:"exclude_fstypes"` // 未配置时排除 tmpfs、overlay 等
+	IncludeMountpoints []string `mapstructure:"include_mountpoints"`
+	ExcludeMountpoints []string `mapstructure:"exclude_mountpoints"`
+}
+
+type SinksConfig struct {
+	Enabled     []string        `mapstructure:"enabled"`      // 存储目标，可同时配置多个：mysql,file
+	AutoMigrate bool            `mapstructure:"auto_migrate"` // 首次写入前自动建表并补齐缺失的字段与索引（mysql），默认开启
+	File        FileSinkConfig  `mapstructure:"file"`
+	Spool       SpoolSinkConfig `mapstructure:"spool"`
+}
+
+// SpoolSinkConfig 数据库不可用时的本地暂存
+type SpoolSinkConfig struct {
+	Enabled     bool   `mapstructure:"enabled"`
+	Dir         string `mapstructure:"dir"`          // 暂存目录
+	SegmentSize int    `mapstructure:"segment_size"` // 单个分段文件大小上限，单位MB
+	MaxSize     int    `mapstructure:"max_size"`     // 暂存总大小上限，单位MB，超出丢弃最旧的数据
+}
+
+type FileSinkConfig struct {
+	Path string `mapstructure:"path"` // file 存储的文件路径
+}
+
+type MetricsConfig struct {
+	Listen string `mapstructure:"listen"` // Prometheus /metrics 监听地址，为空不启用，如 :9100
+	Path   string `mapstructure:"path"`   // Prometheus 指标路径，默认 /metrics
+}
+
+type AlertsConfig struct {
+	Rules []RuleConfig `mapstructure:"rules"`
+}
+
+// RuleConfig 告警规则，表达式形如 disk_usage > 90 for 5m，可用 {label="value"} 过滤带标签的指标
+type RuleConfig struct {
+	Name       string               `mapstructure:"name"`
+	Expr       string               `mapstructure:"expr"`
+	Severity   string               `mapstructure:"severity"`    // 告警级别，默认 warning
+	Summary    string               `mapstructure:"summary"`     // 告警说明
+	Recover    *float64             `mapstructure:"recover"`     // 恢复阈值，用于防抖，如 > 90 告警、回落到 85 以下才恢复
+	ResolveFor time.Duration        `mapstructure:"resolve_for"` // 恢复条件需持续的时间
+	Nodes      map[int]NodeOverride `mapstructure:"nodes"`       // 按节点覆盖
+}
+
+type NodeOverride struct {
+	Threshold *float64       `mapstructure:"threshold"`
+	For       *time.Duration `mapstructure:"for"`
+	Disabled  bool           `mapstructure:"disabled"`
+}
+
+// NotifierConfig 告警通知渠道
+type NotifierConfig struct {
+	Name          string            `mapstructure:"name"`
+	Type          string            `mapstructure:"type"`           // dingtalk / wecom / feishu / webhook / smtp
+	URL           string            `mapstructure:"url"`            // 机器人或 webhook 地址
+	Secret        string            `mapstructure:"secret"`         // 钉钉、飞书加签密钥
+	Headers       map[string]string `mapstructure:"headers"`        // 通用 webhook 附加请求头
+	Template      string            `mapstructure:"template"`       // 消息模板（text/template），为空使用默认模板
+	Timeout       time.Duration     `mapstructure:"timeout"`        // 单次请求超时，默认10秒
+	Retries       int               `mapstructure:"retries"`        // 失败重试次数
+	RetryInterval time.Duration     `mapstructure:"retry_interval"` // 首次重试间隔，之后指数退避，默认5秒
+	RateLimit     int               `mapstructure:"rate_limit"`     // 每分钟最多发送的消息数，0 不限制
+	SMTP          SMTPConfig        `mapstructure:"smtp"`
+}
+
+// SMTPConfig 邮件通知
+type SMTPConfig struct {
+	Host               string        `mapstructure:"host"`
+	Port               int           `mapstructure:"port"`     // 默认 starttls/none 为25，tls 为465
+	Username           string        `mapstructure:"username"` // 为空不认证，否则使用 PLAIN 认证
+	Password           string        `mapstructure:"password"`
+	From               string        `mapstructure:"from"`
+	To                 []string      `mapstructure:"to"`
+	TLS                string        `mapstructure:"tls"`                  // starttls（默认，服务端支持时升级）/ tls（465 直连）/ none
+	InsecureSkipVerify bool          `mapstructure:"insecure_skip_verify"` // 跳过证书校验
+	Subject            string        `mapstructure:"subject"`              // 主题模板，为空使用默认主题
+	Digest             time.Duration `mapstructure:"digest"`               // 摘要间隔，0 表示逐条发送
+}
+
+type LogConfig struct {
+	Dir        string `mapstructure:"dir"`         // 日志目录，默认 /var/log
+	Level      string `mapstructure:"level"`       // 日志级别 debug/info/warn/error
+	MaxSize    int    `mapstructure:"max_size"`    // 单文件最大MB
+	MaxBackups int    `mapstructure:"max_backups"` // 保留备份数
+	MaxAge     int    `mapstructure:"max_age"`     // 日志保留天数
+	Compress   bool   `mapstructure:"compress"`    // 压缩旧日志
+}
+
+func (c DBConfig) DSN() string {
+	return c.Username + ":" + c.Password + "@tcp(" + c.Host + ":" + strconv.Itoa(c.Port) + ")/" + c.Name + "?charset=utf8mb4&parseTime=True&loc=Local"
 }
 
 var (
@@ -25,51 +269,217 @@ var (
 	config *Config
 )
 
+// envKeys env 格式配置中的扁平键与结构化键的对应关系；
+// 未列出的键可使用双下划线表示层级，如 COLLECTORS__INTERVALS__DISK=5m
+var envKeys = map[string]string{
+	"db_host":             "db.host",
+	"db_port":             "db.port",
+	"db_username":         "db.username",
+	"db_password":         "db.password",
+	"db_name":             "db.name",
+	"web_node":            "node",
+	"enabled_collectors":  "collectors.enabled",
+	"disabled_collectors": "collectors.disabled",
+	"ping_privileged":     "collectors.ping.privileged",
+	"sinks":               "sinks.enabled",
+	"auto_migrate":        "sinks.auto_migrate",
+	"sink_file_path":      "sinks.file.path",
+	"spool_enabled":       "sinks.spool.enabled",
+	"spool_dir":           "sinks.spool.dir",
+	"spool_max_size":      "sinks.spool.max_size",
+	"metrics_listen":      "metrics.listen",
+	"metrics_path":        "metrics.path",
+	"log_dir":             "log.dir",
+	"log_level":           "log.level",
+}
+
 func init() {
 	// 全局日志
-	InitLogger(GlobalLogName, util.LogPath(GlobalLogName))
-	SetConfig()
+	InitLogger(GlobalLogName, util.LogPath("", GlobalLogName))
 }
 
-func SetConfig() {
+// Load 读取 -f 指定的配置文件（支持 env/yaml/toml），并初始化数据库连接
+func Load(file string) error {
+	v := viper.New()
+	setDefaults(v)
 
-	viper.AddConfigPath(".")
-	viper.SetConfigFile(".env")
-	viper.SetConfigType("env")
-	err := viper.ReadInConfig()
-	if err != nil {
-		GetLogger(GlobalLogName).Error("viper读取配置失败", zap.Error(err))
-		panic(fmt.Errorf("viper读取配置失败: %s \n", err))
+	// yaml/toml/json 按扩展名识别，其余（如 .env、web.env）一律按 env 格式读取
+	structured := false
+	switch strings.TrimPrefix(filepath.Ext(file), ".") {
+	case "yaml", "yml", "toml", "json":
+		structured = true
+	default:
+		v.SetConfigType("env")
+	}
+
+	v.SetConfigFile(file)
+	if err := v.ReadInConfig(); err != nil {
+		GetLogger(GlobalLogName).Error("viper读取配置失败", zap.String("file", file), zap.Error(err))
+		return fmt.Errorf("viper读取配置失败: %w", err)
+	}
+
+	if !structured {
+		expandEnvKeys(v)
+	}
+
+	cfg := new(Config)
+	if err := v.Unmarshal(cfg); err != nil {
+		GetLogger(GlobalLogName).Error("解析配置失败", zap.String("file", file), zap.Error(err))
+		return fmt.Errorf("解析配置失败: %w", err)
+	}
+	if cfg.Interval < time.Minute {
+		cfg.Interval = time.Minute
+	}
+	if err := validate(cfg); err != nil {
+		GetLogger(GlobalLogName).Error("配置无效", zap.String("file", file), zap.Error(err))
+		return err
 	}
+	config = cfg
 
-	config = &Config{
-		DbHost:     viper.GetString("DB_HOST"),
-		DbUsername: viper.GetString("DB_USERNAME"),
-		DbPassword: viper.GetString("DB_PASSWORD"),
-		DbName:     viper.GetString("DB_NAME"),
-		DbPort:     viper.GetInt("DB_PORT"),
-		WebNode:    viper.GetInt("WEB_NODE"),
+	return openDb()
+}
+
+// validate 校验探测目标名称：名称是子表主键的一部分，为空的行会被丢弃，重复会导致整个快照写入失败
+func validate(cfg *Config) error {
+	unique := func(kind string, names []string) error {
+		seen := make(map[string]bool, len(names))
+		for i, name := range names {
+			if name == "" {
+				return fmt.Errorf("%s 第 %d 项未配置 name", kind, i+1)
+			}
+			if seen[name] {
+				return fmt.Errorf("%s 名称重复: %s", kind, name)
+			}
+			seen[name] = true
+		}
+		return nil
+	}
+
+	collectors := &cfg.Collectors
+	pings := make([]string, 0, len(collectors.Ping.Targets))
+	for i, target := range collectors.Ping.Targets {
+		if target.Host == "" {
+			return fmt.Errorf("ping 第 %d 项未配置 host", i+1)
+		}
+		if target.Name == "" {
+			collectors.Ping.Targets[i].Name = target.Host
+		}
+		pings = append(pings, collectors.Ping.Targets[i].Name)
 	}
-	if config.DbHost == "" {
-		config.DbHost = "localhost"
+	if err := unique("ping", pings); err != nil {
+		return err
 	}
-	if config.DbUsername == "" {
-		config.DbUsername = "root"
+
+	probes := make([]string, 0, len(collectors.Probes))
+	for _, probe := range collectors.Probes {
+		probes = append(probes, probe.Name)
 	}
-	if config.DbPort == 0 {
-		config.DbPort = 3306
+	if err := unique("probes", probes); err != nil {
+		return err
 	}
 
-	dsn := config.DbUsername + ":" + config.DbPassword + "@tcp(" + config.DbHost + ":" + strconv.Itoa(config.DbPort) + ")/" + config.DbName + "?charset=utf8mb4&parseTime=True&loc=Local"
+	certs := make([]string, 0, len(collectors.Certs))
+	for i, cert := range collectors.Certs {
+		if cert.Name == "" {
+			collectors.Certs[i].Name = cert.Address + cert.File
+		}
+		certs = append(certs, collectors.Certs[i].Name)
+	}
+	return unique("certs", certs)
+}
 
-	db, err = gorm.Open(mysql.Open(dsn), &gorm.Config{
-		NamingStrategy: schema.NamingStrategy{IdentifierMaxLength: 64, SingularTable: true},
+func setDefaults(v *viper.Viper) {
+	v.SetDefault("db.host", "localhost")
+	v.SetDefault("db.port", 3306)
+	v.SetDefault("db.username", "root")
+	v.SetDefault("interval", time.Minute)
+	v.SetDefault("collectors.ping.privileged", true)
+	v.SetDefault("collectors.ping.count", 5)
+	v.SetDefault("collectors.ping.interval", time.Second)
+	v.SetDefault("collectors.ping.timeout", 6*time.Second)
+	v.SetDefault("mysql.processlist.threshold", 10*time.Second)
+	v.SetDefault("mysql.processlist.max_rows", 50)
+	v.SetDefault("mysql.processlist.sql_length", 1024)
+	v.SetDefault("mysql.digest.top_n", 20)
+	v.SetDefault("mysql.digest.order_by", "latency")
+	v.SetDefault("mysql.digest.text_length", 1024)
+	v.SetDefault("mysql.status.rates", []string{
+		"Aborted_clients", "Aborted_connects", "Connection_errors_*", "Connections",
+		"Created_tmp_disk_tables", "Created_tmp_tables", "Select_full_join", "Select_scan", "Sort_merge_passes",
+		"Com_select", "Com_insert", "Com_update", "Com_delete", "Com_replace", "Com_commit", "Com_rollback",
+		"Bytes_received", "Bytes_sent", "Table_locks_waited",
+	})
+	v.SetDefault("mysql.status.gauges", []string{"Max_used_connections", "Threads_cached", "Threads_created", "Open_tables", "Open_files"})
+	v.SetDefault("mysql.status.variables", []string{"max_connections", "table_open_cache", "thread_cache_size"})
+	v.SetDefault("sinks.enabled", []string{"mysql"})
+	v.SetDefault("sinks.auto_migrate", true)
+	v.SetDefault("sinks.spool.enabled", true)
+	v.SetDefault("sinks.spool.dir", "/var/lib/server-monitor/spool")
+	v.SetDefault("sinks.spool.segment_size", 8)
+	v.SetDefault("sinks.spool.max_size", 512)
+	v.SetDefault("metrics.path", "/metrics")
+	v.SetDefault("log.dir", "/var/log")
+	v.SetDefault("log.level", "debug")
+	v.SetDefault("log.max_size", 10)
+	v.SetDefault("log.max_backups", 3)
+	v.SetDefault("log.max_age", 30)
+	v.SetDefault("log.compress", true)
+}
+
+// expandEnvKeys 将 env 格式的扁平键映射为结构化键，空值保留默认配置
+func expandEnvKeys(v *viper.Viper) {
+	for _, key := range v.AllKeys() {
+		if v.GetString(key) == "" {
+			continue
+		}
+		if target, ok := envKeys[key]; ok {
+			v.Set(target, v.Get(key))
+		} else if strings.Contains(key, "__") {
+			v.Set(strings.ReplaceAll(key, "__", "."), v.Get(key))
+		}
+	}
+}
+
+// openDb 打开存储库连接；不在启动时检查连通性，数据库暂不可用时由本地暂存兜底
+func openDb() error {
+	var err error
+	db, err = gorm.Open(dialector(config.DB.DSN()), &gorm.Config{
+		NamingStrategy:       schema.NamingStrategy{IdentifierMaxLength: 64, SingularTable: true},
+		DisableAutomaticPing: true,
 	})
 
 	if err != nil {
 		GetLogger(GlobalLogName).Error("初始化gorm发生错误", zap.Error(err))
-		panic("Gorm init error: " + err.Error())
+		return fmt.Errorf("Gorm init error: %w", err)
+	}
+	return nil
+}
+
+// dialector 跳过初始化时查询服务器版本，否则数据库不可用时 gorm.Open 仍会失败
+func dialector(dsn string) gorm.Dialector {
+	return mysql.New(mysql.Config{DSN: dsn, SkipInitializeWithVersion: true})
+}
+
+// OpenMysql 打开被监控实例的连接，与存储库连接相互独立；
+// 不在启动时检查连通性，单个实例不可用不影响其他实例
+func OpenMysql(dsn string) (*gorm.DB, error) {
+	conn, err := gorm.Open(dialector(dsn), &gorm.Config{
+		NamingStrategy:         schema.NamingStrategy{IdentifierMaxLength: 64, SingularTable: true},
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := conn.DB()
+	if err != nil {
+		return nil, err
 	}
+	sqlDB.SetMaxOpenConns(4)
+	sqlDB.SetMaxIdleConns(2)
+	sqlDB.SetConnMaxIdleTime(5 * time.Minute)
+	return conn, nil
 }
 
 func GetConfig() *Config {
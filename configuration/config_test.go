@@ -0,0 +1,51 @@
+package configuration
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  CollectorsConfig
+		err  string
+	}{
+		{"empty", CollectorsConfig{}, ""},
+		{"ping name defaults to host", CollectorsConfig{Ping: PingConfig{Targets: []PingTarget{{Host: "10.0.0.1"}, {Name: "gw", Host: "10.0.0.1"}}}}, ""},
+		{"ping duplicate after default", CollectorsConfig{Ping: PingConfig{Targets: []PingTarget{{Host: "10.0.0.1"}, {Name: "10.0.0.1", Host: "10.0.0.2"}}}}, "ping 名称重复"},
+		{"ping missing host", CollectorsConfig{Ping: PingConfig{Targets: []PingTarget{{Name: "gw"}}}}, "未配置 host"},
+		{"probe missing name", CollectorsConfig{Probes: []ProbeConfig{{Name: "web"}, {URL: "http://x"}}}, "probes 第 2 项未配置 name"},
+		{"probe duplicate", CollectorsConfig{Probes: []ProbeConfig{{Name: "web"}, {Name: "web"}}}, "probes 名称重复"},
+		{"cert duplicate", CollectorsConfig{Certs: []CertConfig{{Address: "a:443"}, {Name: "a:443", File: "/x.pem"}}}, "certs 名称重复"},
+	}
+	for _, tt := range tests {
+		cfg := &Config{Collectors: tt.cfg}
+		err := validate(cfg)
+		if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
+
+// 存储库不可用时仍可打开连接，由本地暂存兜底
+func TestOpenDbWhileDown(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("无法监听 TCP: %v", err)
+	}
+	addr := l.Addr().(*net.TCPAddr)
+	l.Close()
+
+	old := config
+	config = &Config{DB: DBConfig{Host: "127.0.0.1", Port: addr.Port, Username: "monitor", Name: "monitor"}}
+	t.Cleanup(func() { config = old })
+
+	if err := openDb(); err != nil {
+		t.Fatalf("openDb: %v", err)
+	}
+	if GetDb() == nil {
+		t.Fatal("连接未初始化")
+	}
+}
@@ -67,13 +67,15 @@ func InitLogger(cmdName, logPath string) *zap.Logger {
 		return logger // 避免重复创建
 	}
 
+	opts := logOptions()
+
 	// 1. 配置日志切割器 (Lumberjack)
 	lumberjackLogger := &lumberjack.Logger{
-		Filename:   logPath, // 如: "./logs/commandA.log"
-		MaxSize:    10,      // 单文件最大100MB
-		MaxBackups: 3,       // 保留3个备份
-		MaxAge:     30,      // 日志保留30天
-		Compress:   true,    // 压缩旧日志
+		Filename:   logPath,         // 如: "./logs/commandA.log"
+		MaxSize:    opts.MaxSize,    // 单文件最大MB
+		MaxBackups: opts.MaxBackups, // 保留备份数
+		MaxAge:     opts.MaxAge,     // 日志保留天数
+		Compress:   opts.Compress,   // 压缩旧日志
 	}
 	lumberjackLoggers[cmdName] = lumberjackLogger
 
@@ -95,8 +97,12 @@ func InitLogger(cmdName, logPath string) *zap.Logger {
 	})
 
 	// 4. 分离核心（Core）
-	consoleCore := zapcore.NewCore(consoleEncoder, consoleSyncer, zapcore.DebugLevel)
-	fileCore := zapcore.NewCore(fileEncoder, fileSyncer, zapcore.DebugLevel)
+	level, err := zapcore.ParseLevel(opts.Level)
+	if err != nil {
+		level = zapcore.DebugLevel
+	}
+	consoleCore := zapcore.NewCore(consoleEncoder, consoleSyncer, level)
+	fileCore := zapcore.NewCore(fileEncoder, fileSyncer, level)
 	combinedCore := zapcore.NewTee(consoleCore, fileCore)
 
 	// 5. 创建命令专属Logger（添加命令名作为全局字段）
@@ -107,6 +113,14 @@ func InitLogger(cmdName, logPath string) *zap.Logger {
 	return logger
 }
 
+// logOptions 配置加载前使用默认日志参数
+func logOptions() LogConfig {
+	if config != nil {
+		return config.Log
+	}
+	return LogConfig{Level: "debug", MaxSize: 10, MaxBackups: 3, MaxAge: 30, Compress: true}
+}
+
 func GetLogger(cmdName string) *zap.Logger {
 	return loggers[cmdName]
 }
@@ -0,0 +1,193 @@
+package monitor
+
+import (
+	"context"
+	"time"
+
+	"github.com/solvewer/server-monitor/util"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// LogMonitor 二进制日志与重做日志，每个实例一行，写入子表 server_monitor_mysql_log；大小单位为字节
+type LogMonitor struct {
+	Node                int       `gorm:"column:node;primaryKey"`
+	Instance            string    `gorm:"column:instance;primaryKey"`
+	LogBin              bool      `gorm:"column:log_bin"`
+	BinlogFiles         int       `gorm:"column:binlog_files"`
+	BinlogSize          uint64    `gorm:"column:binlog_size"`         // 所有 binlog 文件合计
+	BinlogCurrentSize   uint64    `gorm:"column:binlog_current_size"` // 当前写入的文件
+	BinlogWriteRate     float64   `gorm:"column:binlog_write_rate"`   // 字节/秒
+	BinlogExpireSeconds int64     `gorm:"column:binlog_expire_seconds"`
+	BinlogRetainedSize  float64   `gorm:"column:binlog_retained_size"` // 按当前写入速率在过期时间内保留的大小，未设置过期时为0
+	MaxBinlogSize       uint64    `gorm:"column:max_binlog_size"`
+	RedoCapacity        uint64    `gorm:"column:redo_capacity"`
+	RedoUsed            uint64    `gorm:"column:redo_used"`  // 检查点年龄，尚未刷盘的重做日志
+	RedoUsage           float64   `gorm:"column:redo_usage"` // %，超过约75%时 InnoDB 开始强制刷脏
+	CreatedAt           time.Time `gorm:"column:created_at;primaryKey"`
+}
+
+const (
+	logTable  = "server_monitor_mysql_log"
+	logPrefix = "log_"
+)
+
+func init() {
+	RegisterTable(logTable, LogMonitor{})
+}
+
+// binlogCollector 读取 SHOW BINARY LOGS 与过期配置，按文件大小的增量计算 binlog 写入速率；
+// 重做日志使用量为检查点年龄占重做日志容量的比例
+type binlogCollector struct {
+	db       *gorm.DB
+	status   *statusCache
+	innodb   *innodbStatusCache
+	counters counterScope
+	logger   *zap.Logger
+
+	last time.Time // 上次读取 binlog 列表的时间
+}
+
+func (*binlogCollector) Name() string            { return "mysql_binlog" }
+func (*binlogCollector) Interval() time.Duration { return 0 }
+
+func (c *binlogCollector) Collect(ctx context.Context) ([]Sample, error) {
+	status, variables, _, err := c.status.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	logBin, _ := lookup(variables, "log_bin")
+	samples := []Sample{gauge(logPrefix+"log_bin", logBin)}
+	if logBin != 0 {
+		// 过期配置与文件大小上限来自系统变量，不依赖 SHOW BINARY LOGS
+		expire := binlogExpire(variables)
+		samples = append(samples, gauge(logPrefix+"binlog_expire_seconds", expire))
+		if v, ok := lookup(variables, "max_binlog_size"); ok {
+			samples = append(samples, gauge(logPrefix+"max_binlog_size", v))
+		}
+
+		binlog, err := c.binlog(ctx, expire)
+		if err != nil {
+			// SHOW BINARY LOGS 需要 REPLICATION CLIENT 权限，失败时只记录日志
+			c.logger.Warn("读取 binlog 列表失败", zap.Error(err))
+		}
+		samples = append(samples, binlog...)
+	}
+	return append(samples, c.redo(ctx, status, variables)...), nil
+}
+
+// binlogExpire binlog 过期秒数：8.0 为 binlog_expire_logs_seconds，5.7 及 MariaDB 为 expire_logs_days，
+// 0 表示从不自动清理
+func binlogExpire(variables map[string]float64) float64 {
+	if expire, _ := lookup(variables, "binlog_expire_logs_seconds"); expire != 0 {
+		return expire
+	}
+	days, _ := lookup(variables, "expire_logs_days")
+	return days * 86400
+}
+
+// binlog 读取 binlog 文件列表，输出文件数、大小与写入速率
+func (c *binlogCollector) binlog(ctx context.Context, expire float64) ([]Sample, error) {
+	rows, err := c.db.WithContext(ctx).Raw("SHOW BINARY LOGS").Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		files   int
+		total   uint64
+		current uint64
+		written float64
+		now     = time.Now()
+		primed  = !c.last.IsZero()
+	)
+	for rows.Next() {
+		// 8.0 增加了 Encrypted 列
+		var (
+			name string
+			size uint64
+		)
+		dest := []any{&name, &size}
+		for range columns[2:] {
+			dest = append(dest, new(any))
+		}
+		if err = rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		files++
+		total += size
+		current = size
+
+		// 已清理的文件不再出现，不影响写入量；首次读取之后新出现的文件全部计为本周期写入
+		delta, _, status := c.counters.observe("binlog:"+name, float64(size), now, counter64)
+		switch {
+		case status == counterOK:
+			written += delta
+		case status == counterNoBase && primed:
+			written += float64(size)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	samples := []Sample{
+		gauge(logPrefix+"binlog_files", float64(files)),
+		gauge(logPrefix+"binlog_size", float64(total)),
+		gauge(logPrefix+"binlog_current_size", float64(current)),
+	}
+	if primed {
+		rate := util.ToDouble(written / now.Sub(c.last).Seconds())
+		samples = append(samples,
+			gauge(logPrefix+"binlog_write_rate", rate),
+			gauge(logPrefix+"binlog_retained_size", util.ToDouble(rate*expire)),
+		)
+	}
+	c.last = now
+	return samples, nil
+}
+
+// redo 重做日志容量：8.0.30 起为 innodb_redo_log_capacity，之前为单个文件大小乘以文件数
+func (c *binlogCollector) redo(ctx context.Context, status, variables map[string]float64) []Sample {
+	capacity, ok := lookup(status, "Innodb_redo_log_capacity_resized")
+	if !ok {
+		capacity, ok = lookup(variables, "innodb_redo_log_capacity")
+	}
+	if !ok {
+		size, _ := lookup(variables, "innodb_log_file_size")
+		files, _ := lookup(variables, "innodb_log_files_in_group")
+		capacity = size * files
+	}
+	if capacity <= 0 {
+		return nil
+	}
+	samples := []Sample{gauge(logPrefix+"redo_capacity", capacity)}
+
+	// 8.0.30 起可直接从状态变量取 LSN，之前的版本从 SHOW ENGINE INNODB STATUS 解析
+	lsn, ok1 := lookup(status, "Innodb_redo_log_current_lsn")
+	checkpoint, ok2 := lookup(status, "Innodb_redo_log_checkpoint_lsn")
+	if !ok1 || !ok2 {
+		text, err := c.innodb.get(ctx)
+		if err != nil {
+			c.logger.Warn("读取 InnoDB 状态失败", zap.Error(err))
+			return samples
+		}
+		values := parseInnodbStatus(text)
+		lsn, ok1 = values["Log sequence number"]
+		checkpoint, ok2 = values["Last checkpoint at"]
+		if !ok1 || !ok2 {
+			return samples
+		}
+	}
+	used := max(lsn-checkpoint, 0)
+	return append(samples,
+		gauge(logPrefix+"redo_used", used),
+		gauge(logPrefix+"redo_usage", util.ToDouble(used*100/capacity)),
+	)
+}
@@ -0,0 +1,27 @@
+package monitor
+
+import "testing"
+
+func TestBinlogExpire(t *testing.T) {
+	tests := []struct {
+		name      string
+		variables map[string]string
+		want      float64
+	}{
+		{"8.0", map[string]string{"binlog_expire_logs_seconds": "604800", "expire_logs_days": "0"}, 604800},
+		{"5.7", map[string]string{"expire_logs_days": "7"}, 7 * 86400},
+		// 8.0 两者同时设置时以秒为准
+		{"8.0 兼容配置", map[string]string{"binlog_expire_logs_seconds": "86400", "expire_logs_days": "7"}, 86400},
+		{"从不清理", map[string]string{"binlog_expire_logs_seconds": "0", "expire_logs_days": "0"}, 0},
+		{"performance_schema 大写名称", map[string]string{"BINLOG_EXPIRE_LOGS_SECONDS": "3600"}, 3600},
+	}
+	for _, tt := range tests {
+		variables := make(map[string]float64)
+		for name, value := range tt.variables {
+			putNumeric(variables, name, value)
+		}
+		if got := binlogExpire(variables); got != tt.want {
+			t.Errorf("%s: binlogExpire = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
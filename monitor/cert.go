@@ -0,0 +1,206 @@
+package monitor
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/solvewer/server-monitor/configuration"
+	"github.com/solvewer/server-monitor/util"
+	"go.uber.org/zap"
+)
+
+// CertMonitor TLS 证书有效期，写入子表 server_monitor_cert
+type CertMonitor struct {
+	Node          int       `gorm:"column:node;primaryKey"`
+	Cert          string    `gorm:"column:cert;primaryKey"`
+	Target        string    `gorm:"column:target"` // host:port 或证书文件路径
+	Subject       string    `gorm:"column:subject"`
+	Issuer        string    `gorm:"column:issuer"`
+	NotAfter      int64     `gorm:"column:not_after"` // 到期时间（Unix 秒）
+	DaysLeft      float64   `gorm:"column:days_left"`
+	ChainDaysLeft float64   `gorm:"column:chain_days_left"` // 证书链中最早到期的剩余天数
+	ChainValid    bool      `gorm:"column:chain_valid"`
+	CreatedAt     time.Time `gorm:"column:created_at;primaryKey"`
+}
+
+const (
+	certTable  = "server_monitor_cert"
+	certPrefix = "cert_"
+)
+
+func init() {
+	RegisterTable(certTable, CertMonitor{})
+}
+
+// certCollector 连接 host:port（可指定 SNI）或读取本地 PEM 文件，检查证书到期时间与证书链；
+// 证书变化缓慢，默认每小时采集一次
+type certCollector struct {
+	certs []configuration.CertConfig
+}
+
+func newCertCollector(certs []configuration.CertConfig) *certCollector {
+	for i, cert := range certs {
+		if cert.Name == "" {
+			certs[i].Name = cert.Address + cert.File
+		}
+		if cert.Timeout <= 0 {
+			certs[i].Timeout = 10 * time.Second
+		}
+	}
+	return &certCollector{certs: certs}
+}
+
+func (*certCollector) Name() string            { return "certs" }
+func (*certCollector) Interval() time.Duration { return time.Hour }
+
+func (c *certCollector) Collect(ctx context.Context) ([]Sample, error) {
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		samples []Sample
+		errs    []error
+	)
+	for _, cfg := range c.certs {
+		wg.Add(1)
+		go func(cfg configuration.CertConfig) {
+			defer wg.Done()
+
+			result, err := checkCert(ctx, cfg)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", cfg.Name, err))
+				return
+			}
+			samples = append(samples, result...)
+		}(cfg)
+	}
+	wg.Wait()
+
+	return samples, errors.Join(errs...)
+}
+
+func checkCert(ctx context.Context, cfg configuration.CertConfig) ([]Sample, error) {
+	var (
+		chain  []*x509.Certificate
+		target string
+		err    error
+	)
+	if cfg.File != "" {
+		target = cfg.File
+		chain, err = readPemChain(cfg.File)
+	} else {
+		target = cfg.Address
+		chain, err = fetchChain(ctx, cfg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	leaf := chain[0]
+	now := time.Now()
+	chainNotAfter := leaf.NotAfter
+	for _, cert := range chain[1:] {
+		if cert.NotAfter.Before(chainNotAfter) {
+			chainNotAfter = cert.NotAfter
+		}
+	}
+
+	// 用系统根证书校验证书链；配置了 SNI 时同时校验域名
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+	opts := x509.VerifyOptions{Intermediates: intermediates, CurrentTime: now}
+	if cfg.ServerName != "" {
+		opts.DNSName = cfg.ServerName
+	} else if cfg.File == "" {
+		opts.DNSName, _, _ = net.SplitHostPort(cfg.Address)
+	}
+	_, verifyErr := leaf.Verify(opts)
+	if verifyErr != nil {
+		webLogger.Warn("证书链校验失败", zap.String("cert", cfg.Name), zap.Error(verifyErr))
+	}
+
+	daysLeft := util.ToDouble(leaf.NotAfter.Sub(now).Hours() / 24)
+	webLogger.Info("证书有效期", zap.String("cert", cfg.Name), zap.Time("NotAfter", leaf.NotAfter), zap.Float64("DaysLeft", daysLeft))
+
+	// 主题与签发者在续期后可能变化，只写入子表，不作为序列标识
+	labels := map[string]string{"cert": cfg.Name, "target": target}
+	attrs := map[string]string{"subject": commonName(leaf.Subject), "issuer": commonName(leaf.Issuer)}
+	return []Sample{
+		{Name: certPrefix + "not_after", Value: float64(leaf.NotAfter.Unix()), Labels: labels, Attrs: attrs},
+		{Name: certPrefix + "days_left", Value: daysLeft, Labels: labels},
+		{Name: certPrefix + "chain_days_left", Value: util.ToDouble(chainNotAfter.Sub(now).Hours() / 24), Labels: labels},
+		{Name: certPrefix + "chain_valid", Value: boolValue(verifyErr == nil), Labels: labels},
+	}, nil
+}
+
+// commonName 优先取 CN，没有 CN 时使用完整的 DN
+func commonName(name pkix.Name) string {
+	if name.CommonName != "" {
+		return name.CommonName
+	}
+	return name.String()
+}
+
+// fetchChain 建立 TLS 连接获取对端证书链，不在握手阶段校验以便记录无效证书
+func fetchChain(ctx context.Context, cfg configuration.CertConfig) ([]*x509.Certificate, error) {
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	serverName := cfg.ServerName
+	if serverName == "" {
+		serverName, _, _ = net.SplitHostPort(cfg.Address)
+	}
+	dialer := &tls.Dialer{Config: &tls.Config{ServerName: serverName, InsecureSkipVerify: true}}
+	conn, err := dialer.DialContext(ctx, "tcp", cfg.Address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	chain := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(chain) == 0 {
+		return nil, errors.New("对端未返回证书")
+	}
+	return chain, nil
+}
+
+// readPemChain 读取 PEM 文件中的全部证书，第一个为叶子证书
+func readPemChain(file string) ([]*x509.Certificate, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var chain []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, cert)
+	}
+	if len(chain) == 0 {
+		return nil, errors.New("文件中没有证书")
+	}
+	return chain, nil
+}
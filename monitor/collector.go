@@ -0,0 +1,302 @@
+package monitor
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Sample 采集器产生的单个指标样本
+type Sample struct {
+	Name   string            `json:"name"`             // 指标名，与表字段名保持一致，如 cpu_usage
+	Value  float64           `json:"value"`            // 指标值
+	Labels map[string]string `json:"labels,omitempty"` // 可选标签，如 mountpoint、device；无标签的样本写入主表
+	// Attrs 附加的状态信息，如解析结果、错误信息；只写入子表同名的字符串列，
+	// 不作为序列标识，不参与告警序列的区分，也不输出为 Prometheus 标签
+	Attrs map[string]string `json:"attrs,omitempty"`
+}
+
+// Collector 指标采集器，新增指标只需实现该接口并注册到 Registry
+type Collector interface {
+	// Name 采集器名称，用于配置中启用/禁用
+	Name() string
+	// Interval 采集间隔，0 表示每个统计周期都采集
+	Interval() time.Duration
+	// Collect 执行一次采集
+	Collect(ctx context.Context) ([]Sample, error)
+}
+
+// Registry 采集器注册表，负责按间隔调度采集器并汇总样本
+type Registry struct {
+	mu         sync.Mutex
+	collectors []Collector
+	enabled    map[string]bool
+	disabled   map[string]bool
+	intervals  map[string]time.Duration
+	lastRun    map[string]time.Time
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		enabled:   make(map[string]bool),
+		disabled:  make(map[string]bool),
+		intervals: make(map[string]time.Duration),
+		lastRun:   make(map[string]time.Time),
+	}
+}
+
+// Register 注册采集器，同名采集器后注册的覆盖先注册的
+func (r *Registry) Register(collectors ...Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, c := range collectors {
+		replaced := false
+		for i, exist := range r.collectors {
+			if exist.Name() == c.Name() {
+				r.collectors[i] = c
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			r.collectors = append(r.collectors, c)
+		}
+	}
+}
+
+// Configure 设置启用/禁用列表及采集间隔覆盖，enabled 为空表示启用全部
+func (r *Registry) Configure(enabled, disabled []string, intervals map[string]time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.enabled = make(map[string]bool)
+	r.disabled = make(map[string]bool)
+	for _, name := range enabled {
+		r.enabled[strings.TrimSpace(name)] = true
+	}
+	for _, name := range disabled {
+		r.disabled[strings.TrimSpace(name)] = true
+	}
+	r.intervals = make(map[string]time.Duration, len(intervals))
+	for name, interval := range intervals {
+		r.intervals[name] = interval
+	}
+}
+
+// Collectors 返回当前启用的采集器
+func (r *Registry) Collectors() []Collector {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list := make([]Collector, 0, len(r.collectors))
+	for _, c := range r.collectors {
+		if r.isEnabled(c.Name()) {
+			list = append(list, c)
+		}
+	}
+	return list
+}
+
+// Names 返回当前启用的采集器名称
+func (r *Registry) Names() []string {
+	collectors := r.Collectors()
+	names := make([]string, 0, len(collectors))
+	for _, c := range collectors {
+		names = append(names, c.Name())
+	}
+	return names
+}
+
+func (r *Registry) isEnabled(name string) bool {
+	if r.disabled[name] {
+		return false
+	}
+	return len(r.enabled) == 0 || r.enabled[name]
+}
+
+// due 判断采集器在 t 时刻是否需要采集，并记录本次采集时间
+func (r *Registry) due(c Collector, t time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	interval := c.Interval()
+	if override, ok := r.intervals[c.Name()]; ok {
+		interval = override
+	}
+	last, ok := r.lastRun[c.Name()]
+	// 预留1秒误差，避免 ticker 抖动导致整周期被跳过
+	if ok && interval > 0 && t.Sub(last) < interval-time.Second {
+		return false
+	}
+	r.lastRun[c.Name()] = t
+	return true
+}
+
+// Collect 并发执行所有到期的采集器，汇总样本；单个采集器失败只记录日志
+func (r *Registry) Collect(ctx context.Context, t time.Time, logger *zap.Logger) []Sample {
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		samples []Sample
+	)
+
+	for _, c := range r.Collectors() {
+		if !r.due(c, t) {
+			continue
+		}
+
+		wg.Add(1)
+		go func(c Collector) {
+			defer wg.Done()
+
+			result, err := c.Collect(ctx)
+			if err != nil {
+				logger.Error("采集器执行失败", zap.String("collector", c.Name()), zap.Error(err))
+			}
+
+			mu.Lock()
+			samples = append(samples, result...)
+			mu.Unlock()
+		}(c)
+	}
+	wg.Wait()
+
+	return samples
+}
+
+// gauge 构造无标签样本
+func gauge(name string, value float64) Sample {
+	return Sample{Name: name, Value: value}
+}
+
+// unlabelled 过滤出无标签样本，用于填充主表
+func unlabelled(samples []Sample) []Sample {
+	var list []Sample
+	for _, s := range samples {
+		if len(s.Labels) == 0 {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// withLabels 为样本附加公共标签，同名时样本自身的标签优先，避免实例 tags 覆盖子表的分组键
+func withLabels(samples []Sample, labels map[string]string) []Sample {
+	list := make([]Sample, 0, len(samples))
+	for _, s := range samples {
+		merged := make(map[string]string, len(s.Labels)+len(labels))
+		for k, v := range labels {
+			merged[k] = v
+		}
+		for k, v := range s.Labels {
+			merged[k] = v
+		}
+		s.Labels = merged
+		list = append(list, s)
+	}
+	return list
+}
+
+// fill 将样本按 gorm column 标签填充到结构体中，样本名需为 prefix+列名；
+// labels 及样本 Attrs 中与字符串列同名的项一并填充
+func fill(record any, prefix string, samples []Sample, labels map[string]string) {
+	v := reflect.ValueOf(record).Elem()
+	columns := make(map[string]reflect.Value, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		if column := columnName(v.Type().Field(i)); column != "" {
+			columns[column] = v.Field(i)
+		}
+	}
+
+	for _, s := range samples {
+		for k, value := range s.Attrs {
+			if field, ok := columns[k]; ok && field.Kind() == reflect.String {
+				field.SetString(value)
+			}
+		}
+
+		name, ok := strings.CutPrefix(s.Name, prefix)
+		if !ok {
+			continue
+		}
+		field, ok := columns[name]
+		if !ok {
+			continue
+		}
+		switch field.Kind() {
+		case reflect.Float32, reflect.Float64:
+			field.SetFloat(s.Value)
+		case reflect.Int, reflect.Int32, reflect.Int64:
+			field.SetInt(int64(s.Value))
+		case reflect.Uint, reflect.Uint32, reflect.Uint64:
+			field.SetUint(uint64(s.Value))
+		case reflect.Bool:
+			field.SetBool(s.Value != 0)
+		}
+	}
+
+	for k, value := range labels {
+		if field, ok := columns[k]; ok && field.Kind() == reflect.String {
+			field.SetString(value)
+		}
+	}
+}
+
+// setColumn 按列名设置字段值，类型不匹配时忽略
+func setColumn(record any, column string, value any) {
+	v := reflect.ValueOf(record).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if columnName(v.Type().Field(i)) != column {
+			continue
+		}
+		if val := reflect.ValueOf(value); val.Type().AssignableTo(v.Field(i).Type()) {
+			v.Field(i).Set(val)
+		}
+		return
+	}
+}
+
+// childRecords 将名称以 prefix 开头的带标签样本按 key 标签分组，每组生成一行子表数据
+func childRecords[T any](table, prefix, key string, samples []Sample, node int, t time.Time) []Record {
+	var (
+		order  []string
+		groups = make(map[string][]Sample)
+		labels = make(map[string]map[string]string)
+	)
+	for _, s := range samples {
+		if !strings.HasPrefix(s.Name, prefix) || s.Labels[key] == "" {
+			continue
+		}
+		k := s.Labels[key]
+		if _, ok := groups[k]; !ok {
+			order = append(order, k)
+			labels[k] = s.Labels
+		}
+		groups[k] = append(groups[k], s)
+	}
+
+	records := make([]Record, 0, len(order))
+	for _, k := range order {
+		row := new(T)
+		fill(row, prefix, groups[k], labels[k])
+		setColumn(row, "node", node)
+		setColumn(row, "created_at", t)
+		records = append(records, Record{Table: table, Value: row})
+	}
+	return records
+}
+
+// columnName 解析 gorm 标签中的列名
+func columnName(field reflect.StructField) string {
+	for _, part := range strings.Split(field.Tag.Get("gorm"), ";") {
+		if name, ok := strings.CutPrefix(part, "column:"); ok {
+			return name
+		}
+	}
+	return ""
+}
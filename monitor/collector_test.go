@@ -0,0 +1,59 @@
+package monitor
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWithLabels(t *testing.T) {
+	samples := []Sample{
+		{Name: "replica_io_running", Value: 1, Labels: map[string]string{"channel": "ch1"}},
+		{Name: "threads_connected", Value: 10},
+	}
+	tags := map[string]string{"instance": "primary", "role": "primary", "channel": "tag"}
+
+	got := withLabels(samples, tags)
+	want := []map[string]string{
+		{"instance": "primary", "role": "primary", "channel": "ch1"},
+		{"instance": "primary", "role": "primary", "channel": "tag"},
+	}
+	for i := range got {
+		if !reflect.DeepEqual(got[i].Labels, want[i]) {
+			t.Errorf("%s labels = %v, want %v", got[i].Name, got[i].Labels, want[i])
+		}
+	}
+	if len(samples[1].Labels) != 0 {
+		t.Errorf("原样本标签被修改: %v", samples[1].Labels)
+	}
+}
+
+func TestChildRecordsAttrs(t *testing.T) {
+	labels := map[string]string{"instance": "primary", "channel": "default"}
+	samples := []Sample{
+		{Name: "replica_io_running", Value: 1, Labels: labels, Attrs: map[string]string{"io_status": "Connecting", "source_log_file": "binlog.000042"}},
+		{Name: "replica_seconds_behind", Value: 12, Labels: labels},
+		{Name: "replica_gtid_gap", Value: 3, Labels: labels},
+	}
+
+	at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.Local)
+	records := childRecords[ReplicaMonitor](replicaTable, replicaPrefix, "channel", samples, 2, at)
+	if len(records) != 1 {
+		t.Fatalf("records = %d, want 1", len(records))
+	}
+	got := records[0].Value.(*ReplicaMonitor)
+	want := &ReplicaMonitor{
+		Node:          2,
+		Instance:      "primary",
+		Channel:       "default",
+		IoStatus:      "Connecting",
+		IoRunning:     true,
+		SecondsBehind: 12,
+		SourceLogFile: "binlog.000042",
+		GtidGap:       3,
+		CreatedAt:     at,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("record = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,123 @@
+package monitor
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// counterStatus 一次观测累计计数的结果
+type counterStatus int
+
+const (
+	counterOK     counterStatus = iota
+	counterNoBase               // 首次出现或时间未前进，没有可用的基准值
+	counterReset                // 计数回退：实例重启、网卡重建、统计被清空
+)
+
+// counterWidth 计数器位宽与设备能力，决定回退时按回绕还是重置处理
+type counterWidth struct {
+	wrap    bool    // 是否为可能回绕的 32 位计数
+	maxRate float64 // 设备能达到的最大每秒增量，按回绕计算的速率超过该值时视为重置
+}
+
+// counter64 回退一律视为重置，如 Mysql 状态变量、内核 64 位计数
+var counter64 = counterWidth{}
+
+// counter32 上次值不超过 32 位时，回退按回绕处理的前提是回绕后的速率不超过 maxRate（如链路速率）；
+// maxRate 未知（为0）时无法区分回绕与重置，一律视为重置，避免输出约 4GB 的虚假增量
+func counter32(maxRate float64) counterWidth {
+	return counterWidth{wrap: true, maxRate: maxRate}
+}
+
+// counterStaleAfter 超过该时长未更新的计数（已删除的网卡、被淘汰的语句摘要）会被清理
+const counterStaleAfter = 25 * time.Hour
+
+type counterKey struct {
+	instance string
+	metric   string
+}
+
+type counterPoint struct {
+	value float64
+	at    time.Time
+}
+
+// counterStore 所有采集器共享的累计计数状态，按 指标+实例 保存上次的值与采样时间，
+// 以两次采样的实际间隔计算每秒速率，漏采的周期由实际间隔自然摊平
+type counterStore struct {
+	mu     sync.Mutex
+	points map[counterKey]counterPoint
+	pruned time.Time
+}
+
+var counters = &counterStore{points: make(map[counterKey]counterPoint)}
+
+// observe 记录当前值并返回相对上次的增量与间隔秒数；重置时以当前值作为新的基准
+func (s *counterStore) observe(key counterKey, value float64, at time.Time, width counterWidth) (float64, float64, counterStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prune(at)
+	last, ok := s.points[key]
+	s.points[key] = counterPoint{value: value, at: at}
+	if !ok {
+		return 0, 0, counterNoBase
+	}
+
+	seconds := at.Sub(last.at).Seconds()
+	if seconds <= 0 {
+		return 0, 0, counterNoBase
+	}
+	if value >= last.value {
+		return value - last.value, seconds, counterOK
+	}
+	if width.wrap && width.maxRate > 0 && last.value <= math.MaxUint32 {
+		if wrapped := math.MaxUint32 - last.value + value + 1; wrapped/seconds <= width.maxRate {
+			return wrapped, seconds, counterOK
+		}
+	}
+	return 0, seconds, counterReset
+}
+
+// prune 每小时清理一次长时间未更新的计数
+func (s *counterStore) prune(now time.Time) {
+	if now.Sub(s.pruned) < time.Hour {
+		return
+	}
+	s.pruned = now
+	for key, point := range s.points {
+		if now.Sub(point.at) > counterStaleAfter {
+			delete(s.points, key)
+		}
+	}
+}
+
+func (s *counterStore) scope(instance string) counterScope {
+	return counterScope{store: s, instance: instance}
+}
+
+// counterScope 绑定实例的计数视图，Mysql 实例按实例名区分，本机采集器按采集器名区分
+type counterScope struct {
+	store    *counterStore
+	instance string
+}
+
+func (c counterScope) observe(metric string, value float64, at time.Time, width counterWidth) (float64, float64, counterStatus) {
+	return c.store.observe(counterKey{instance: c.instance, metric: metric}, value, at, width)
+}
+
+// rate 每秒速率，首次观测与计数重置时返回 false
+func (c counterScope) rate(metric string, value float64, at time.Time) (float64, bool) {
+	delta, seconds, status := c.observe(metric, value, at, counter64)
+	if status != counterOK {
+		return 0, false
+	}
+	return delta / seconds, true
+}
+
+// delta 相对上次观测的增量，首次观测与计数重置时返回 false
+func (c counterScope) delta(metric string, value float64, at time.Time) (float64, bool) {
+	delta, _, status := c.observe(metric, value, at, counter64)
+	return delta, status == counterOK
+}
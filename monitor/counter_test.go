@@ -0,0 +1,101 @@
+package monitor
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCounterObserve(t *testing.T) {
+	const gbit = 1e9 / 8
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.Local)
+	tests := []struct {
+		name    string
+		width   counterWidth
+		values  []float64
+		seconds float64
+		delta   float64
+		status  counterStatus
+	}{
+		{"首次观测", counter64, []float64{100}, 0, 0, counterNoBase},
+		{"递增", counter64, []float64{100, 700}, 60, 600, counterOK},
+		{"64位回退为重置", counter64, []float64{math.MaxUint32 - 10, 5}, 60, 0, counterReset},
+		{"32位回绕在链路速率内", counter32(gbit), []float64{math.MaxUint32 - 10, 5}, 60, 16, counterOK},
+		// 回绕后约 4GB/分钟，超过 100Mbit 链路的能力，只能是重置
+		{"32位回绕超过链路速率", counter32(100e6 / 8), []float64{1000, 5}, 60, 0, counterReset},
+		{"32位速率未知为重置", counter32(0), []float64{math.MaxUint32 - 10, 5}, 60, 0, counterReset},
+		{"上次值超过32位为重置", counter32(gbit), []float64{math.MaxUint32 + 10, 5}, 60, 0, counterReset},
+		{"时间未前进", counter64, []float64{100, 200}, 0, 0, counterNoBase},
+	}
+	for _, tt := range tests {
+		store := &counterStore{points: make(map[counterKey]counterPoint)}
+		scope := store.scope("test")
+
+		var (
+			delta, seconds float64
+			status         counterStatus
+		)
+		for i, v := range tt.values {
+			at := start.Add(time.Duration(float64(i)*tt.seconds) * time.Second)
+			delta, seconds, status = scope.observe("c", v, at, tt.width)
+		}
+		if status != tt.status || delta != tt.delta {
+			t.Errorf("%s: delta = %v, status = %v, want %v, %v", tt.name, delta, status, tt.delta, tt.status)
+		}
+		if status == counterOK && seconds != tt.seconds {
+			t.Errorf("%s: seconds = %v, want %v", tt.name, seconds, tt.seconds)
+		}
+	}
+}
+
+func TestCounterResetRebase(t *testing.T) {
+	scope := (&counterStore{points: make(map[counterKey]counterPoint)}).scope("mysql")
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.Local)
+
+	if _, ok := scope.rate("Queries", 5000, start); ok {
+		t.Error("首次观测不应输出速率")
+	}
+	// 实例重启，计数从头开始
+	if _, ok := scope.rate("Queries", 10, start.Add(time.Minute)); ok {
+		t.Error("计数重置不应输出速率")
+	}
+	// 重置后以当前值作为新的基准
+	if rate, ok := scope.rate("Queries", 130, start.Add(2*time.Minute)); !ok || rate != 2 {
+		t.Errorf("rate = %v, %v, want 2, true", rate, ok)
+	}
+	// 不同实例的计数互不影响
+	other := scope.store.scope("other")
+	if _, ok := other.delta("Queries", 130, start.Add(2*time.Minute)); ok {
+		t.Error("其他实例首次观测不应输出增量")
+	}
+}
+
+func TestLinkWidths(t *testing.T) {
+	dir := t.TempDir()
+	old := sysNetDir
+	sysNetDir = dir
+	t.Cleanup(func() { sysNetDir = old })
+
+	write := func(name, speed string) {
+		if err := os.MkdirAll(filepath.Join(dir, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name, "speed"), []byte(speed), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("eth0", "1000\n")
+	write("bond0", "-1\n")
+
+	bytes, packets := linkWidths("eth0")
+	if !bytes.wrap || bytes.maxRate != 125e6 || packets.maxRate != 125e6/84 {
+		t.Errorf("eth0 = %+v, %+v", bytes, packets)
+	}
+	for _, name := range []string{"bond0", "missing"} {
+		if bytes, packets := linkWidths(name); bytes.maxRate != 0 || packets.maxRate != 0 {
+			t.Errorf("%s = %+v, %+v, want unknown", name, bytes, packets)
+		}
+	}
+}
@@ -0,0 +1,344 @@
+package monitor
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// DeadlockMonitor 最近一次死锁中的事务，每个事务一行，写入子表 server_monitor_mysql_deadlock
+type DeadlockMonitor struct {
+	Node       int       `gorm:"column:node;primaryKey"`
+	Instance   string    `gorm:"column:instance;primaryKey"`
+	Txn        int       `gorm:"column:txn;primaryKey"` // 死锁信息中的事务序号
+	DetectedAt string    `gorm:"column:detected_at"`    // 数据库服务器时间
+	TrxId      string    `gorm:"column:trx_id"`
+	ThreadId   uint64    `gorm:"column:thread_id"`
+	User       string    `gorm:"column:user"`
+	Host       string    `gorm:"column:host"`
+	ActiveTime int       `gorm:"column:active_time"` // 事务已执行秒数
+	Statement  string    `gorm:"column:statement;type:text"`
+	Tables     string    `gorm:"column:tables"`
+	HoldsLock  string    `gorm:"column:holds_lock;type:text"`
+	WaitsLock  string    `gorm:"column:waits_lock;type:text"`
+	RolledBack bool      `gorm:"column:rolled_back"`
+	CreatedAt  time.Time `gorm:"column:created_at;primaryKey"`
+}
+
+// LockWaitMonitor 当前的行锁等待，写入子表 server_monitor_mysql_lock_wait
+type LockWaitMonitor struct {
+	Node           int       `gorm:"column:node;primaryKey"`
+	Instance       string    `gorm:"column:instance;primaryKey"`
+	WaitingTrxId   string    `gorm:"column:waiting_trx_id;primaryKey"`
+	BlockingTrxId  string    `gorm:"column:blocking_trx_id;primaryKey"`
+	WaitingThread  uint64    `gorm:"column:waiting_thread"`
+	WaitingQuery   string    `gorm:"column:waiting_query;type:text"`
+	WaitTime       int       `gorm:"column:wait_time"` // 秒
+	BlockingThread uint64    `gorm:"column:blocking_thread"`
+	BlockingQuery  string    `gorm:"column:blocking_query;type:text"` // 阻塞事务当前执行的语句，空闲时为空
+	ObjectSchema   string    `gorm:"column:object_schema"`
+	ObjectName     string    `gorm:"column:object_name"`
+	IndexName      string    `gorm:"column:index_name"`
+	LockType       string    `gorm:"column:lock_type"`
+	LockMode       string    `gorm:"column:lock_mode"`
+	CreatedAt      time.Time `gorm:"column:created_at;primaryKey"`
+}
+
+const (
+	deadlockTable  = "server_monitor_mysql_deadlock"
+	deadlockPrefix = "deadlock_"
+	lockWaitTable  = "server_monitor_mysql_lock_wait"
+	lockWaitPrefix = "lock_wait_"
+
+	// lockWaitLimit 每个周期最多记录的锁等待数
+	lockWaitLimit = 100
+	// statementLength 语句截断长度
+	statementLength = 2048
+)
+
+func init() {
+	RegisterTable(deadlockTable, DeadlockMonitor{})
+	RegisterTable(lockWaitTable, LockWaitMonitor{})
+}
+
+var (
+	deadlockSectionRegexp  = regexp.MustCompile(`(?s)LATEST DETECTED DEADLOCK\n-+\n(.*?)\n-+\n[A-Z][A-Z /]+\n-+\n`)
+	deadlockTimeRegexp     = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2})`)
+	deadlockHeaderRegexp   = regexp.MustCompile(`(?m)^\*\*\* \((\d+)\) ([A-Z ()']+):$`)
+	deadlockRollbackRegexp = regexp.MustCompile(`\*\*\* WE ROLL BACK TRANSACTION \((\d+)\)`)
+	trxRegexp              = regexp.MustCompile(`TRANSACTION (\d+), ACTIVE (\d+) sec`)
+	threadRegexp           = regexp.MustCompile(`MySQL thread id (\d+), OS thread handle \S+, query id \d+ ?(.*)`)
+	recordLockRegexp       = regexp.MustCompile(`index (\S+) of table (\S+) .*?lock[ _]mode (.+?)(?: waiting)?$`) // 排他锁为 lock_mode X，共享锁为 lock mode S
+	tableLockRegexp        = regexp.MustCompile(`TABLE LOCK table (\S+) .*?lock[ _]mode (.+?)(?: waiting)?$`)
+)
+
+// deadlockTxn 死锁信息中的一个事务
+type deadlockTxn struct {
+	txn        int
+	trxId      string
+	threadId   uint64
+	user       string
+	host       string
+	active     int
+	statement  string
+	tables     []string
+	holds      []string
+	waits      []string
+	rolledBack bool
+}
+
+// parseDeadlock 解析 SHOW ENGINE INNODB STATUS 中的 LATEST DETECTED DEADLOCK 段，
+// 返回死锁发生时间与涉及的事务；没有死锁信息时返回空
+func parseDeadlock(status string) (string, []*deadlockTxn) {
+	m := deadlockSectionRegexp.FindStringSubmatch(status)
+	if m == nil {
+		return "", nil
+	}
+	section := m[1]
+	detected := ""
+	if t := deadlockTimeRegexp.FindStringSubmatch(section); t != nil {
+		detected = t[1]
+	}
+
+	rolledBack := 0
+	if r := deadlockRollbackRegexp.FindStringSubmatch(section); r != nil {
+		rolledBack, _ = strconv.Atoi(r[1])
+	}
+
+	var (
+		txns  []*deadlockTxn
+		byNum = make(map[int]*deadlockTxn)
+	)
+	headers := deadlockHeaderRegexp.FindAllStringSubmatchIndex(section, -1)
+	for i, h := range headers {
+		num, _ := strconv.Atoi(section[h[2]:h[3]])
+		kind := section[h[4]:h[5]]
+		end := len(section)
+		if i+1 < len(headers) {
+			end = headers[i+1][0]
+		}
+		body := section[h[1]:end]
+		if idx := strings.Index(body, "*** WE ROLL BACK"); idx >= 0 {
+			body = body[:idx]
+		}
+
+		txn, ok := byNum[num]
+		if !ok {
+			txn = &deadlockTxn{txn: num, rolledBack: num == rolledBack}
+			byNum[num] = txn
+			txns = append(txns, txn)
+		}
+
+		switch {
+		case kind == "TRANSACTION":
+			txn.parseTransaction(body)
+		case strings.HasPrefix(kind, "HOLDS"):
+			txn.holds = append(txn.holds, txn.parseLocks(body)...)
+		case strings.HasPrefix(kind, "WAITING"):
+			txn.waits = append(txn.waits, txn.parseLocks(body)...)
+		}
+	}
+	return detected, txns
+}
+
+func (t *deadlockTxn) parseTransaction(body string) {
+	if m := trxRegexp.FindStringSubmatch(body); m != nil {
+		t.trxId = m[1]
+		t.active, _ = strconv.Atoi(m[2])
+	}
+
+	lines := strings.Split(strings.TrimSpace(body), "\n")
+	var statement []string
+	for i, line := range lines {
+		m := threadRegexp.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		t.threadId, _ = strconv.ParseUint(m[1], 10, 64)
+		// 线程行末尾依次为 host、user、state
+		if fields := strings.Fields(m[2]); len(fields) >= 2 {
+			t.host, t.user = fields[0], fields[1]
+		}
+		// 线程行之后直到锁信息之前为正在执行的语句
+		for _, next := range lines[i+1:] {
+			if strings.HasPrefix(next, "RECORD LOCKS") || strings.HasPrefix(next, "TABLE LOCK") {
+				break
+			}
+			statement = append(statement, next)
+		}
+		break
+	}
+	t.statement = truncate(strings.TrimSpace(strings.Join(statement, "\n")), statementLength)
+
+	// 5.7 在事务段内直接输出等待的锁
+	t.waits = append(t.waits, t.parseLocks(body)...)
+}
+
+// parseLocks 提取锁信息为 库.表(索引) 模式 的形式，并记录涉及的表
+func (t *deadlockTxn) parseLocks(body string) []string {
+	var locks []string
+	for _, line := range strings.Split(body, "\n") {
+		var table, desc string
+		if m := recordLockRegexp.FindStringSubmatch(line); m != nil {
+			table = strings.ReplaceAll(m[2], "`", "")
+			desc = table + "(" + strings.ReplaceAll(m[1], "`", "") + ") " + m[3]
+		} else if m := tableLockRegexp.FindStringSubmatch(line); m != nil {
+			table = strings.ReplaceAll(m[1], "`", "")
+			desc = table + " " + m[2]
+		} else {
+			continue
+		}
+		locks = append(locks, desc)
+		if !containsString(t.tables, table) {
+			t.tables = append(t.tables, table)
+		}
+	}
+	return locks
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// deadlockKey 死锁的唯一标识：发生时间只精确到秒，同一秒内的多次死锁以涉及的事务区分
+func deadlockKey(detected string, txns []*deadlockTxn) string {
+	ids := make([]string, 0, len(txns))
+	for _, t := range txns {
+		ids = append(ids, t.trxId)
+	}
+	return detected + "|" + strings.Join(ids, ",")
+}
+
+// locksCollector 发现新的死锁时记录涉及的事务、表、锁与语句，并记录当前的行锁等待；
+// 以死锁发生时间与事务判断是否为新死锁，启动后首次采集会记录已有的最近一次死锁
+type locksCollector struct {
+	db       *gorm.DB
+	innodb   *innodbStatusCache
+	logger   *zap.Logger
+	detected string // 上次记录的死锁，见 deadlockKey
+	noWaits  bool   // 不支持 performance_schema.data_lock_waits（8.0 之前的版本）或没有查询权限
+}
+
+func (*locksCollector) Name() string            { return "mysql_locks" }
+func (*locksCollector) Interval() time.Duration { return 0 }
+
+func (c *locksCollector) Collect(ctx context.Context) ([]Sample, error) {
+	var samples []Sample
+
+	status, err := c.innodb.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if detected, txns := parseDeadlock(status); detected != "" && deadlockKey(detected, txns) != c.detected {
+		c.detected = deadlockKey(detected, txns)
+		c.logger.Warn("发现新的死锁", zap.String("DetectedAt", detected), zap.Int("transactions", len(txns)))
+		for _, t := range txns {
+			// 语句与锁信息只写入子表，不作为序列标识
+			labels := map[string]string{"txn": strconv.Itoa(t.txn)}
+			attrs := map[string]string{
+				"detected_at": detected,
+				"trx_id":      t.trxId,
+				"user":        t.user,
+				"host":        t.host,
+				"statement":   t.statement,
+				"tables":      strings.Join(t.tables, ","),
+				"holds_lock":  strings.Join(t.holds, "; "),
+				"waits_lock":  strings.Join(t.waits, "; "),
+			}
+			samples = append(samples,
+				Sample{Name: deadlockPrefix + "txn", Value: float64(t.txn), Labels: labels, Attrs: attrs},
+				Sample{Name: deadlockPrefix + "thread_id", Value: float64(t.threadId), Labels: labels},
+				Sample{Name: deadlockPrefix + "active_time", Value: float64(t.active), Labels: labels},
+				Sample{Name: deadlockPrefix + "rolled_back", Value: boolValue(t.rolledBack), Labels: labels},
+			)
+		}
+	}
+
+	if c.noWaits {
+		return samples, nil
+	}
+	waits, err := c.lockWaits(ctx)
+	if mysqlErrorIs(err, 1044, 1142, 1146, 1227) {
+		c.noWaits = true
+		c.logger.Warn("不支持或无权读取锁等待，不再采集锁等待", zap.Error(err))
+		return samples, nil
+	}
+	if err != nil {
+		// 连接中断、超时等错误下个周期重试
+		c.logger.Warn("读取锁等待失败", zap.Error(err))
+		return samples, nil
+	}
+	return append(samples, waits...), nil
+}
+
+// lockWaits 读取 performance_schema.data_lock_waits，关联等待与阻塞事务的语句及等待的锁
+func (c *locksCollector) lockWaits(ctx context.Context) ([]Sample, error) {
+	rows, err := c.db.WithContext(ctx).Raw(
+		"SELECT CAST(r.trx_id AS CHAR), r.trx_mysql_thread_id, IFNULL(r.trx_query, ''), "+
+			"IFNULL(TIMESTAMPDIFF(SECOND, r.trx_wait_started, NOW()), 0), "+
+			"CAST(b.trx_id AS CHAR), b.trx_mysql_thread_id, IFNULL(b.trx_query, ''), "+
+			"IFNULL(l.OBJECT_SCHEMA, ''), IFNULL(l.OBJECT_NAME, ''), IFNULL(l.INDEX_NAME, ''), l.LOCK_TYPE, l.LOCK_MODE "+
+			"FROM performance_schema.data_lock_waits w "+
+			"JOIN information_schema.INNODB_TRX r ON r.trx_id = w.REQUESTING_ENGINE_TRANSACTION_ID "+
+			"JOIN information_schema.INNODB_TRX b ON b.trx_id = w.BLOCKING_ENGINE_TRANSACTION_ID "+
+			"JOIN performance_schema.data_locks l ON l.ENGINE_LOCK_ID = w.REQUESTING_ENGINE_LOCK_ID "+
+			"ORDER BY r.trx_wait_started LIMIT ?", lockWaitLimit).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		samples []Sample
+		count   int
+		longest int
+	)
+	for rows.Next() {
+		var (
+			waitingTrx, waitingQuery, blockingTrx, blockingQuery string
+			schema, object, index, lockType, lockMode            string
+			waitingThread, blockingThread                        uint64
+			waitTime                                             int
+		)
+		if err = rows.Scan(&waitingTrx, &waitingThread, &waitingQuery, &waitTime, &blockingTrx, &blockingThread, &blockingQuery,
+			&schema, &object, &index, &lockType, &lockMode); err != nil {
+			return nil, err
+		}
+		count++
+		longest = max(longest, waitTime)
+
+		labels := map[string]string{"wait": waitingTrx + "/" + blockingTrx}
+		attrs := map[string]string{
+			"waiting_trx_id":  waitingTrx,
+			"waiting_query":   truncate(waitingQuery, statementLength),
+			"blocking_trx_id": blockingTrx,
+			"blocking_query":  truncate(blockingQuery, statementLength),
+			"object_schema":   schema,
+			"object_name":     object,
+			"index_name":      index,
+			"lock_type":       lockType,
+			"lock_mode":       lockMode,
+		}
+		samples = append(samples,
+			Sample{Name: lockWaitPrefix + "waiting_thread", Value: float64(waitingThread), Labels: labels, Attrs: attrs},
+			Sample{Name: lockWaitPrefix + "blocking_thread", Value: float64(blockingThread), Labels: labels},
+			Sample{Name: lockWaitPrefix + "wait_time", Value: float64(waitTime), Labels: labels},
+		)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// 不带 wait 标签的汇总，用于告警
+	return append(samples, gauge("lock_waits", float64(count)), gauge("lock_waits_longest", float64(longest))), nil
+}
@@ -0,0 +1,165 @@
+package monitor
+
+import (
+	"reflect"
+	"testing"
+)
+
+// 5.7 的 SHOW ENGINE INNODB STATUS 片段：事务 (1) 只输出等待的锁
+const innodbStatus57 = `
+=====================================
+2024-03-05 10:15:50 0x7f3a2c1f9700 INNODB MONITOR OUTPUT
+=====================================
+------------------------
+LATEST DETECTED DEADLOCK
+------------------------
+2024-03-05 10:15:42 0x7f3a2c1f9700
+*** (1) TRANSACTION:
+TRANSACTION 421937, ACTIVE 12 sec starting index read
+mysql tables in use 1, locked 1
+LOCK WAIT 2 lock struct(s), heap size 1136, 1 row lock(s)
+MySQL thread id 8, OS thread handle 139887622231808, query id 120 localhost root updating
+UPDATE accounts SET balance = balance - 10 WHERE id = 2
+*** (1) WAITING FOR THIS LOCK TO BE GRANTED:
+RECORD LOCKS space id 25 page no 3 n bits 72 index PRIMARY of table ` + "`bank`.`accounts`" + ` trx id 421937 lock_mode X locks rec but not gap waiting
+Record lock, heap no 3 PHYSICAL RECORD: n_fields 4; compact format; info bits 0
+ 0: len 4; hex 80000002; asc     ;;
+
+*** (2) TRANSACTION:
+TRANSACTION 421938, ACTIVE 8 sec starting index read
+mysql tables in use 1, locked 1
+3 lock struct(s), heap size 1136, 2 row lock(s)
+MySQL thread id 9, OS thread handle 139887621965568, query id 121 10.0.0.21 app updating
+UPDATE accounts
+   SET balance = balance + 10
+ WHERE id = 1
+*** (2) HOLDS THE LOCK(S):
+RECORD LOCKS space id 25 page no 3 n bits 72 index PRIMARY of table ` + "`bank`.`accounts`" + ` trx id 421938 lock mode S locks rec but not gap
+Record lock, heap no 3 PHYSICAL RECORD: n_fields 4; compact format; info bits 0
+ 0: len 4; hex 80000002; asc     ;;
+
+*** (2) WAITING FOR THIS LOCK TO BE GRANTED:
+RECORD LOCKS space id 25 page no 3 n bits 72 index PRIMARY of table ` + "`bank`.`accounts`" + ` trx id 421938 lock_mode X locks rec but not gap waiting
+Record lock, heap no 2 PHYSICAL RECORD: n_fields 4; compact format; info bits 0
+ 0: len 4; hex 80000001; asc     ;;
+
+*** WE ROLL BACK TRANSACTION (2)
+------------
+TRANSACTIONS
+------------
+Trx id counter 421940
+`
+
+// 8.0.18 之后每个事务都输出持有的锁，且包含表锁与插入意向锁
+const innodbStatus80 = `
+------------------------
+LATEST DETECTED DEADLOCK
+------------------------
+2024-03-05 10:15:42 140211987064576
+*** (1) TRANSACTION:
+TRANSACTION 10105, ACTIVE 7 sec inserting
+mysql tables in use 1, locked 1
+LOCK WAIT 4 lock struct(s), heap size 1128, 3 row lock(s), undo log entries 1
+MySQL thread id 12, OS thread handle 140211, query id 45 10.0.0.22 app update
+INSERT INTO orders (user_id, amount) VALUES (7, 100)
+
+*** (1) HOLDS THE LOCK(S):
+TABLE LOCK table ` + "`shop`.`orders`" + ` trx id 10105 lock mode IX
+RECORD LOCKS space id 9 page no 5 n bits 80 index idx_user of table ` + "`shop`.`orders`" + ` trx id 10105 lock mode S
+Record lock, heap no 1 PHYSICAL RECORD: n_fields 1; compact format; info bits 0
+
+*** (1) WAITING FOR THIS LOCK TO BE GRANTED:
+RECORD LOCKS space id 9 page no 5 n bits 80 index idx_user of table ` + "`shop`.`orders`" + ` trx id 10105 lock_mode X insert intention waiting
+Record lock, heap no 1 PHYSICAL RECORD: n_fields 1; compact format; info bits 0
+
+*** (2) TRANSACTION:
+TRANSACTION 10106, ACTIVE 5 sec inserting
+mysql tables in use 1, locked 1
+LOCK WAIT 4 lock struct(s), heap size 1128, 3 row lock(s), undo log entries 1
+MySQL thread id 13, OS thread handle 140212, query id 46 10.0.0.23 app update
+INSERT INTO orders (user_id, amount) VALUES (7, 200)
+
+*** (2) HOLDS THE LOCK(S):
+RECORD LOCKS space id 9 page no 5 n bits 80 index idx_user of table ` + "`shop`.`orders`" + ` trx id 10106 lock mode S
+Record lock, heap no 1 PHYSICAL RECORD: n_fields 1; compact format; info bits 0
+
+*** (2) WAITING FOR THIS LOCK TO BE GRANTED:
+RECORD LOCKS space id 9 page no 5 n bits 80 index idx_user of table ` + "`shop`.`orders`" + ` trx id 10106 lock_mode X insert intention waiting
+Record lock, heap no 1 PHYSICAL RECORD: n_fields 1; compact format; info bits 0
+
+*** WE ROLL BACK TRANSACTION (2)
+------------
+TRANSACTIONS
+------------
+`
+
+func TestParseDeadlock(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   string
+		detected string
+		want     []deadlockTxn
+	}{
+		{"5.7", innodbStatus57, "2024-03-05 10:15:42", []deadlockTxn{
+			{
+				txn: 1, trxId: "421937", threadId: 8, host: "localhost", user: "root", active: 12,
+				statement: "UPDATE accounts SET balance = balance - 10 WHERE id = 2",
+				tables:    []string{"bank.accounts"},
+				waits:     []string{"bank.accounts(PRIMARY) X locks rec but not gap"},
+			},
+			{
+				txn: 2, trxId: "421938", threadId: 9, host: "10.0.0.21", user: "app", active: 8,
+				statement:  "UPDATE accounts\n   SET balance = balance + 10\n WHERE id = 1",
+				tables:     []string{"bank.accounts"},
+				holds:      []string{"bank.accounts(PRIMARY) S locks rec but not gap"},
+				waits:      []string{"bank.accounts(PRIMARY) X locks rec but not gap"},
+				rolledBack: true,
+			},
+		}},
+		{"8.0", innodbStatus80, "2024-03-05 10:15:42", []deadlockTxn{
+			{
+				txn: 1, trxId: "10105", threadId: 12, host: "10.0.0.22", user: "app", active: 7,
+				statement: "INSERT INTO orders (user_id, amount) VALUES (7, 100)",
+				tables:    []string{"shop.orders"},
+				holds:     []string{"shop.orders IX", "shop.orders(idx_user) S"},
+				waits:     []string{"shop.orders(idx_user) X insert intention"},
+			},
+			{
+				txn: 2, trxId: "10106", threadId: 13, host: "10.0.0.23", user: "app", active: 5,
+				statement:  "INSERT INTO orders (user_id, amount) VALUES (7, 200)",
+				tables:     []string{"shop.orders"},
+				holds:      []string{"shop.orders(idx_user) S"},
+				waits:      []string{"shop.orders(idx_user) X insert intention"},
+				rolledBack: true,
+			},
+		}},
+		{"没有死锁", "------------\nTRANSACTIONS\n------------\n", "", nil},
+	}
+	for _, tt := range tests {
+		detected, txns := parseDeadlock(tt.status)
+		if detected != tt.detected {
+			t.Errorf("%s: detected = %q, want %q", tt.name, detected, tt.detected)
+		}
+		if len(txns) != len(tt.want) {
+			t.Fatalf("%s: %d transactions, want %d", tt.name, len(txns), len(tt.want))
+		}
+		for i, txn := range txns {
+			if !reflect.DeepEqual(*txn, tt.want[i]) {
+				t.Errorf("%s: txn %d =\n%+v\nwant\n%+v", tt.name, i+1, *txn, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestDeadlockKey(t *testing.T) {
+	_, first := parseDeadlock(innodbStatus80)
+	second := []*deadlockTxn{{txn: 1, trxId: "10107"}, {txn: 2, trxId: "10108"}}
+
+	// 同一秒内的两次死锁需区分
+	if deadlockKey("2024-03-05 10:15:42", first) == deadlockKey("2024-03-05 10:15:42", second) {
+		t.Error("同一秒内不同事务的死锁 key 相同")
+	}
+	if got := deadlockKey("2024-03-05 10:15:42", first); got != "2024-03-05 10:15:42|10105,10106" {
+		t.Errorf("deadlockKey = %q", got)
+	}
+}
@@ -0,0 +1,169 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/solvewer/server-monitor/configuration"
+	"github.com/solvewer/server-monitor/util"
+	"gorm.io/gorm"
+)
+
+// DigestMonitor 本周期开销最大的语句摘要，数值均为周期内增量，写入子表 server_monitor_mysql_digest
+type DigestMonitor struct {
+	Node         int       `gorm:"column:node;primaryKey"`
+	Instance     string    `gorm:"column:instance;primaryKey"`
+	SchemaName   string    `gorm:"column:schema_name;primaryKey"`
+	Digest       string    `gorm:"column:digest;primaryKey"`
+	DigestText   string    `gorm:"column:digest_text;type:text"`
+	Rank         int       `gorm:"column:top_rank"` // 本周期排名，从1开始
+	ExecCount    uint64    `gorm:"column:exec_count"`
+	TotalLatency float64   `gorm:"column:total_latency"` // 毫秒
+	AvgLatency   float64   `gorm:"column:avg_latency"`   // 毫秒
+	RowsExamined uint64    `gorm:"column:rows_examined"`
+	RowsSent     uint64    `gorm:"column:rows_sent"`
+	Errors       uint64    `gorm:"column:errors"`
+	CreatedAt    time.Time `gorm:"column:created_at;primaryKey"`
+}
+
+const (
+	digestTable  = "server_monitor_mysql_digest"
+	digestPrefix = "digest_"
+
+	// picosPerMilli performance_schema 计时单位为皮秒
+	picosPerMilli = 1e9
+)
+
+func init() {
+	RegisterTable(digestTable, DigestMonitor{})
+}
+
+// digestStat 语句摘要的累计值或周期增量
+type digestStat struct {
+	schema       string
+	digest       string
+	text         string
+	count        float64
+	latency      float64
+	rowsExamined float64
+	rowsSent     float64
+	errors       float64
+}
+
+// digestCollector 读取 events_statements_summary_by_digest，计算两次采集之间每个摘要的增量，
+// 按配置的维度排序后输出前 N 条；首次采集只记录基准值
+type digestCollector struct {
+	db       *gorm.DB
+	cfg      configuration.DigestConfig
+	counters counterScope
+	primed   bool // 是否已完成首次采集
+}
+
+func (*digestCollector) Name() string            { return "mysql_digest" }
+func (*digestCollector) Interval() time.Duration { return 0 }
+
+func (c *digestCollector) Collect(ctx context.Context) ([]Sample, error) {
+	rows, err := c.db.WithContext(ctx).Raw(
+		"SELECT IFNULL(SCHEMA_NAME, ''), DIGEST, IFNULL(DIGEST_TEXT, ''), COUNT_STAR, SUM_TIMER_WAIT, SUM_ROWS_EXAMINED, SUM_ROWS_SENT, SUM_ERRORS " +
+			"FROM performance_schema.events_statements_summary_by_digest WHERE DIGEST IS NOT NULL").Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var current []digestStat
+	for rows.Next() {
+		var s digestStat
+		if err = rows.Scan(&s.schema, &s.digest, &s.text, &s.count, &s.latency, &s.rowsExamined, &s.rowsSent, &s.errors); err != nil {
+			return nil, err
+		}
+		current = append(current, s)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	deltas := c.top(current, time.Now())
+	samples := make([]Sample, 0, len(deltas)*7)
+	for i, d := range deltas {
+		// 摘要文本只写入子表，序列仅以库名与摘要区分
+		labels := map[string]string{
+			"statement":   d.schema + ":" + d.digest,
+			"schema_name": d.schema,
+			"digest":      d.digest,
+		}
+		attrs := map[string]string{"digest_text": truncate(d.text, c.cfg.TextLength)}
+		latency := d.latency / picosPerMilli
+		samples = append(samples,
+			Sample{Name: digestPrefix + "top_rank", Value: float64(i + 1), Labels: labels, Attrs: attrs},
+			Sample{Name: digestPrefix + "exec_count", Value: d.count, Labels: labels},
+			Sample{Name: digestPrefix + "total_latency", Value: util.ToDouble(latency), Labels: labels},
+			Sample{Name: digestPrefix + "avg_latency", Value: util.ToDouble(latency / d.count), Labels: labels},
+			Sample{Name: digestPrefix + "rows_examined", Value: d.rowsExamined, Labels: labels},
+			Sample{Name: digestPrefix + "rows_sent", Value: d.rowsSent, Labels: labels},
+			Sample{Name: digestPrefix + "errors", Value: d.errors, Labels: labels},
+		)
+	}
+	return samples, nil
+}
+
+// top 将累计值换算为本周期增量，按配置的维度排序后返回前 N 条
+func (c *digestCollector) top(current []digestStat, now time.Time) []digestStat {
+	primed := c.primed
+	c.primed = true
+
+	var deltas []digestStat
+	for _, curr := range current {
+		key := "digest:" + curr.schema + ":" + curr.digest
+		valid := true
+		for field, value := range map[string]*float64{
+			"count":         &curr.count,
+			"latency":       &curr.latency,
+			"rows_examined": &curr.rowsExamined,
+			"rows_sent":     &curr.rowsSent,
+			"errors":        &curr.errors,
+		} {
+			delta, _, status := c.counters.observe(key+":"+field, *value, now, counter64)
+			switch {
+			case status == counterOK:
+				*value = delta
+			case status == counterReset:
+				// TRUNCATE 或实例重启后累计值从 0 开始，当前值即为增量
+			case primed:
+				// 首次采集之后新出现的摘要，累计值即为本周期增量
+			default:
+				valid = false
+			}
+		}
+		if valid && curr.count > 0 {
+			deltas = append(deltas, curr)
+		}
+	}
+
+	sort.Slice(deltas, func(i, j int) bool {
+		a, b := deltas[i], deltas[j]
+		switch c.cfg.OrderBy {
+		case "count":
+			return a.count > b.count
+		case "rows_examined":
+			return a.rowsExamined > b.rowsExamined
+		default:
+			return a.latency > b.latency
+		}
+	})
+	if len(deltas) > c.cfg.TopN {
+		deltas = deltas[:c.cfg.TopN]
+	}
+	return deltas
+}
+
+// validDigestOrder 校验排序维度
+func validDigestOrder(orderBy string) error {
+	switch orderBy {
+	case "latency", "count", "rows_examined":
+		return nil
+	}
+	return fmt.Errorf("mysql.digest.order_by 无效: %s", orderBy)
+}
@@ -0,0 +1,64 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/solvewer/server-monitor/configuration"
+)
+
+func digestNames(stats []digestStat) []string {
+	names := make([]string, 0, len(stats))
+	for _, s := range stats {
+		names = append(names, s.schema+":"+s.digest)
+	}
+	return names
+}
+
+func TestDigestTop(t *testing.T) {
+	store := &counterStore{points: make(map[counterKey]counterPoint)}
+	c := &digestCollector{cfg: configuration.DigestConfig{TopN: 2, OrderBy: "latency"}, counters: store.scope("test")}
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.Local)
+
+	// 首次采集只记录基准值
+	first := []digestStat{
+		{schema: "shop", digest: "a", count: 100, latency: 5e9, rowsExamined: 1000},
+		{schema: "shop", digest: "b", count: 10, latency: 9e9, rowsExamined: 50},
+		{schema: "crm", digest: "a", count: 1, latency: 1e9, rowsExamined: 1},
+	}
+	if got := c.top(first, start); len(got) != 0 {
+		t.Fatalf("首次采集 = %v, want 空", digestNames(got))
+	}
+
+	second := []digestStat{
+		{schema: "shop", digest: "a", count: 160, latency: 9e9, rowsExamined: 1600}, // 增量 60 次、4e9
+		{schema: "shop", digest: "b", count: 10, latency: 9e9, rowsExamined: 50},    // 无执行，不输出
+		{schema: "crm", digest: "a", count: 3, latency: 2e9, rowsExamined: 9},       // 增量 2 次、1e9
+		{schema: "crm", digest: "c", count: 4, latency: 6e9, rowsExamined: 4},       // 新出现，累计值即增量
+	}
+	got := c.top(second, start.Add(time.Minute))
+	if names := digestNames(got); len(names) != 2 || names[0] != "crm:c" || names[1] != "shop:a" {
+		t.Fatalf("按延迟排序的前 2 条 = %v, want [crm:c shop:a]", names)
+	}
+	if a := got[1]; a.count != 60 || a.latency != 4e9 || a.rowsExamined != 600 {
+		t.Errorf("shop:a 增量 = %+v", a)
+	}
+
+	// TRUNCATE 后累计值回退，当前值即为增量
+	c.cfg.OrderBy = "count"
+	third := []digestStat{
+		{schema: "shop", digest: "a", count: 5, latency: 1e9, rowsExamined: 50},
+		{schema: "crm", digest: "a", count: 10, latency: 3e9, rowsExamined: 30},
+		{schema: "crm", digest: "c", count: 4, latency: 6e9, rowsExamined: 4},
+	}
+	got = c.top(third, start.Add(2*time.Minute))
+	if names := digestNames(got); len(names) != 2 || names[0] != "crm:a" || names[1] != "shop:a" {
+		t.Fatalf("按次数排序的前 2 条 = %v, want [crm:a shop:a]", names)
+	}
+	if a := got[1]; a.count != 5 || a.latency != 1e9 {
+		t.Errorf("重置后 shop:a 增量 = %+v, want 当前累计值", a)
+	}
+	if a := got[0]; a.count != 7 || a.rowsExamined != 21 {
+		t.Errorf("crm:a 增量 = %+v", a)
+	}
+}
@@ -0,0 +1,96 @@
+package monitor
+
+import (
+	"context"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/disk"
+	"github.com/solvewer/server-monitor/configuration"
+	"github.com/solvewer/server-monitor/util"
+	"go.uber.org/zap"
+)
+
+// DiskMonitor 各挂载点的磁盘与 inode 使用情况，写入子表 server_monitor_disk
+type DiskMonitor struct {
+	Node        int       `gorm:"column:node;primaryKey"`
+	Mountpoint  string    `gorm:"column:mountpoint;primaryKey"`
+	Device      string    `gorm:"column:device"`
+	Fstype      string    `gorm:"column:fstype"`
+	Total       uint64    `gorm:"column:total"` // 字节
+	Used        uint64    `gorm:"column:used"`
+	Free        uint64    `gorm:"column:free"`
+	Usage       float64   `gorm:"column:usage"`
+	InodesTotal uint64    `gorm:"column:inodes_total"`
+	InodesUsed  uint64    `gorm:"column:inodes_used"`
+	InodesFree  uint64    `gorm:"column:inodes_free"`
+	InodesUsage float64   `gorm:"column:inodes_usage"`
+	CreatedAt   time.Time `gorm:"column:created_at;primaryKey"`
+}
+
+const (
+	diskTable  = "server_monitor_disk"
+	diskPrefix = "disk_partition_"
+)
+
+// defaultExcludeFstypes 未配置 exclude_fstypes 时排除的内存/只读镜像文件系统
+var defaultExcludeFstypes = []string{"tmpfs", "devtmpfs", "squashfs", "overlay", "iso9660"}
+
+func init() {
+	RegisterTable(diskTable, DiskMonitor{})
+}
+
+// partitionCollector 采集 disk.Partitions 返回的所有挂载点
+type partitionCollector struct {
+	fstypes     nameFilter
+	mountpoints nameFilter
+}
+
+func newPartitionCollector(cfg configuration.PartitionsConfig) *partitionCollector {
+	excludeFstypes := cfg.ExcludeFstypes
+	if excludeFstypes == nil {
+		excludeFstypes = defaultExcludeFstypes
+	}
+	return &partitionCollector{
+		fstypes:     newNameFilter(cfg.IncludeFstypes, excludeFstypes),
+		mountpoints: newNameFilter(cfg.IncludeMountpoints, cfg.ExcludeMountpoints),
+	}
+}
+
+func (*partitionCollector) Name() string            { return "disk_partitions" }
+func (*partitionCollector) Interval() time.Duration { return 0 }
+
+func (c *partitionCollector) Collect(ctx context.Context) ([]Sample, error) {
+	partitions, err := disk.PartitionsWithContext(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var samples []Sample
+	seen := make(map[string]bool)
+	for _, p := range partitions {
+		if seen[p.Mountpoint] || !c.fstypes.match(p.Fstype) || !c.mountpoints.match(p.Mountpoint) {
+			continue
+		}
+		seen[p.Mountpoint] = true
+
+		usage, err := disk.UsageWithContext(ctx, p.Mountpoint)
+		if err != nil {
+			webLogger.Warn("读取挂载点使用情况失败", zap.String("mountpoint", p.Mountpoint), zap.Error(err))
+			continue
+		}
+
+		labels := map[string]string{"mountpoint": p.Mountpoint, "device": p.Device, "fstype": p.Fstype}
+		samples = append(samples,
+			Sample{Name: diskPrefix + "total", Value: float64(usage.Total), Labels: labels},
+			Sample{Name: diskPrefix + "used", Value: float64(usage.Used), Labels: labels},
+			Sample{Name: diskPrefix + "free", Value: float64(usage.Free), Labels: labels},
+			Sample{Name: diskPrefix + "usage", Value: util.ToDouble(usage.UsedPercent), Labels: labels},
+			Sample{Name: diskPrefix + "inodes_total", Value: float64(usage.InodesTotal), Labels: labels},
+			Sample{Name: diskPrefix + "inodes_used", Value: float64(usage.InodesUsed), Labels: labels},
+			Sample{Name: diskPrefix + "inodes_free", Value: float64(usage.InodesFree), Labels: labels},
+			Sample{Name: diskPrefix + "inodes_usage", Value: util.ToDouble(usage.InodesUsedPercent), Labels: labels},
+		)
+		webLogger.Info("挂载点使用情况", zap.String("mountpoint", p.Mountpoint), zap.Float64("Usage", usage.UsedPercent), zap.Float64("InodesUsage", usage.InodesUsedPercent))
+	}
+	return samples, nil
+}
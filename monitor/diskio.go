@@ -0,0 +1,191 @@
+package monitor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/disk"
+	"github.com/solvewer/server-monitor/configuration"
+	"github.com/solvewer/server-monitor/util"
+	"go.uber.org/zap"
+)
+
+// DiskIOMonitor 各块设备的 IO 统计，写入子表 server_monitor_diskio
+type DiskIOMonitor struct {
+	Node           int       `gorm:"column:node;primaryKey"`
+	Device         string    `gorm:"column:device;primaryKey"`
+	ReadBytesRate  float64   `gorm:"column:read_bytes_rate"` // 字节/秒
+	WriteBytesRate float64   `gorm:"column:write_bytes_rate"`
+	ReadIops       float64   `gorm:"column:read_iops"`
+	WriteIops      float64   `gorm:"column:write_iops"`
+	Await          float64   `gorm:"column:await"`       // 平均每次 IO 耗时（毫秒）
+	QueueDepth     float64   `gorm:"column:queue_depth"` // 平均队列深度
+	Util           float64   `gorm:"column:util"`        // 设备繁忙时间占比 %
+	CreatedAt      time.Time `gorm:"column:created_at;primaryKey"`
+}
+
+const (
+	diskIOTable  = "server_monitor_diskio"
+	diskIOPrefix = "diskio_"
+)
+
+// defaultExcludeDevices 未配置 exclude 时排除的虚拟块设备
+var defaultExcludeDevices = []string{"loop*", "ram*", "sr*", "fd*", "zram*"}
+
+func init() {
+	RegisterTable(diskIOTable, DiskIOMonitor{})
+}
+
+// diskIORate 单个设备两次采样之间的速率
+type diskIORate struct {
+	device         string
+	readBytesRate  float64
+	writeBytesRate float64
+	readIops       float64
+	writeIops      float64
+	await          float64
+	queueDepth     float64
+	util           float64
+}
+
+// diskIOSampler 通过共享的计数状态按实际间隔计算各设备速率
+type diskIOSampler struct {
+	devices  nameFilter
+	counters counterScope
+}
+
+// newDiskIOSampler scope 区分不同的使用方（disk_io、mysql_io），互不影响各自的基准值
+func newDiskIOSampler(cfg configuration.DevicesConfig, scope string) *diskIOSampler {
+	exclude := cfg.Exclude
+	if exclude == nil {
+		exclude = defaultExcludeDevices
+	}
+	return &diskIOSampler{devices: newNameFilter(cfg.Include, exclude), counters: counters.scope(scope)}
+}
+
+// sample 返回各设备速率，首次采样与计数器回退的设备不返回
+func (s *diskIOSampler) sample(ctx context.Context) ([]diskIORate, error) {
+	stats, err := disk.IOCountersWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+
+	var rates []diskIORate
+	for name, stat := range stats {
+		if !s.devices.match(name) {
+			continue
+		}
+
+		values := map[string]uint64{
+			"read_bytes":  stat.ReadBytes,
+			"write_bytes": stat.WriteBytes,
+			"reads":       stat.ReadCount,
+			"writes":      stat.WriteCount,
+			"read_time":   stat.ReadTime,
+			"write_time":  stat.WriteTime,
+			"io_time":     stat.IoTime,
+			"weighted_io": stat.WeightedIO,
+		}
+		deltas := make(map[string]float64, len(values))
+		var seconds float64
+		valid := true
+		for counter, value := range values {
+			delta, elapsed, status := s.counters.observe(name+":"+counter, float64(value), now, counter64)
+			if status != counterOK {
+				valid = false
+				continue
+			}
+			deltas[counter], seconds = delta, elapsed
+		}
+		if !valid {
+			continue
+		}
+
+		millis := seconds * 1000
+		rate := diskIORate{
+			device:         name,
+			readBytesRate:  deltas["read_bytes"] / seconds,
+			writeBytesRate: deltas["write_bytes"] / seconds,
+			readIops:       deltas["reads"] / seconds,
+			writeIops:      deltas["writes"] / seconds,
+			queueDepth:     deltas["weighted_io"] / millis,
+			util:           min(deltas["io_time"]/millis*100, 100),
+		}
+		if ios := deltas["reads"] + deltas["writes"]; ios > 0 {
+			rate.await = (deltas["read_time"] + deltas["write_time"]) / ios
+		}
+		rates = append(rates, rate)
+	}
+	return rates, nil
+}
+
+// diskIOCollector 按块设备输出读写吞吐、IOPS、await、队列深度与利用率
+type diskIOCollector struct {
+	sampler *diskIOSampler
+}
+
+func newDiskIOCollector(cfg configuration.DevicesConfig) *diskIOCollector {
+	return &diskIOCollector{sampler: newDiskIOSampler(cfg, "disk_io")}
+}
+
+func (*diskIOCollector) Name() string            { return "disk_io" }
+func (*diskIOCollector) Interval() time.Duration { return 0 }
+
+func (c *diskIOCollector) Collect(ctx context.Context) ([]Sample, error) {
+	rates, err := c.sampler.sample(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var samples []Sample
+	for _, r := range rates {
+		labels := map[string]string{"device": r.device}
+		samples = append(samples,
+			Sample{Name: diskIOPrefix + "read_bytes_rate", Value: util.ToDouble(r.readBytesRate), Labels: labels},
+			Sample{Name: diskIOPrefix + "write_bytes_rate", Value: util.ToDouble(r.writeBytesRate), Labels: labels},
+			Sample{Name: diskIOPrefix + "read_iops", Value: util.ToDouble(r.readIops), Labels: labels},
+			Sample{Name: diskIOPrefix + "write_iops", Value: util.ToDouble(r.writeIops), Labels: labels},
+			Sample{Name: diskIOPrefix + "await", Value: util.ToDouble(r.await), Labels: labels},
+			Sample{Name: diskIOPrefix + "queue_depth", Value: util.ToDouble(r.queueDepth), Labels: labels},
+			Sample{Name: diskIOPrefix + "util", Value: util.ToDouble(r.util), Labels: labels},
+		)
+		webLogger.Info("磁盘IO", zap.String("device", r.device), zap.Float64("Util", r.util), zap.Float64("Await", r.await))
+	}
+	return samples, nil
+}
+
+// sysBlockDir 块设备在 sysfs 中的目录
+var sysBlockDir = "/sys/class/block"
+
+// wholeDisks 只保留物理磁盘：过滤分区（IO 已计入所属磁盘）以及 LVM、软 RAID 等叠加设备
+// （IO 已计入底层磁盘），汇总时避免重复计算；sysfs 不可用时视为整盘
+func wholeDisks(rates []diskIORate) []diskIORate {
+	var list []diskIORate
+	for _, r := range rates {
+		if !isPartition(r.device) && !isStacked(r.device) {
+			list = append(list, r)
+		}
+	}
+	return list
+}
+
+// blockPath 设备名中的 / 在 sysfs 中为 !，如 cciss/c0d0
+func blockPath(device string, elem ...string) string {
+	return filepath.Join(append([]string{sysBlockDir, strings.ReplaceAll(device, "/", "!")}, elem...)...)
+}
+
+// isPartition 分区目录下有 partition 文件
+func isPartition(device string) bool {
+	_, err := os.Stat(blockPath(device, "partition"))
+	return err == nil
+}
+
+// isStacked 叠加设备（dm-*、md*）的 slaves 目录非空
+func isStacked(device string) bool {
+	entries, err := os.ReadDir(blockPath(device, "slaves"))
+	return err == nil && len(entries) > 0
+}
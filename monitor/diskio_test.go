@@ -0,0 +1,53 @@
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWholeDisks(t *testing.T) {
+	dir := t.TempDir()
+	mkdir := func(elem ...string) {
+		if err := os.MkdirAll(filepath.Join(append([]string{dir}, elem...)...), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	touch := func(elem ...string) {
+		if err := os.WriteFile(filepath.Join(append([]string{dir}, elem...)...), []byte("1\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// sda 两个分区，dm-1 与 dm-10 为 sda2 上的 LVM 卷，md1 为 nvme 上的软 RAID
+	for _, dev := range []string{"sda", "nvme0n1", "nvme0n10", "dm-1", "dm-10", "md1", "md10", "cciss!c0d0"} {
+		mkdir(dev, "slaves")
+	}
+	for _, part := range []string{"sda1", "sda2", "nvme0n1p1"} {
+		mkdir(part)
+		touch(part, "partition")
+	}
+	touch("dm-1", "slaves", "sda2")
+	touch("dm-10", "slaves", "sda2")
+	touch("md1", "slaves", "nvme0n1p1")
+
+	old := sysBlockDir
+	sysBlockDir = dir
+	defer func() { sysBlockDir = old }()
+
+	var rates []diskIORate
+	for _, dev := range []string{"sda", "sda1", "sda2", "nvme0n1", "nvme0n1p1", "nvme0n10", "dm-1", "dm-10", "md1", "md10", "cciss/c0d0", "xvda"} {
+		rates = append(rates, diskIORate{device: dev})
+	}
+
+	var got []string
+	for _, r := range wholeDisks(rates) {
+		got = append(got, r.device)
+	}
+	// md10 没有底层设备信息时按整盘计算；sysfs 中不存在的设备同样视为整盘
+	want := []string{"sda", "nvme0n1", "nvme0n10", "md10", "cciss/c0d0", "xvda"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("wholeDisks = %v, want %v", got, want)
+	}
+}
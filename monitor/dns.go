@@ -0,0 +1,365 @@
+package monitor
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/solvewer/server-monitor/configuration"
+	"go.uber.org/zap"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// DnsMonitor DNS 解析探测结果，写入子表 server_monitor_dns，耗时单位为毫秒
+type DnsMonitor struct {
+	Node      int       `gorm:"column:node;primaryKey"`
+	Query     string    `gorm:"column:query;primaryKey"` // 名称/类型@解析服务器
+	Name      string    `gorm:"column:name"`
+	Type      string    `gorm:"column:type"`
+	Resolver  string    `gorm:"column:resolver"`
+	Status    string    `gorm:"column:status"` // NOERROR / NXDOMAIN / SERVFAIL 等，无应答时为 TIMEOUT 或 ERROR
+	Up        bool      `gorm:"column:up"`     // 解析服务器是否返回应答
+	Rcode     int       `gorm:"column:rcode"`
+	Latency   float64   `gorm:"column:latency"`
+	Answers   int       `gorm:"column:answers"`
+	Match     bool      `gorm:"column:match"`
+	CreatedAt time.Time `gorm:"column:created_at;primaryKey"`
+}
+
+const (
+	dnsTable  = "server_monitor_dns"
+	dnsPrefix = "dns_"
+
+	resolvConf = "/etc/resolv.conf"
+)
+
+var dnsTypes = map[string]dnsmessage.Type{
+	"A":     dnsmessage.TypeA,
+	"AAAA":  dnsmessage.TypeAAAA,
+	"CNAME": dnsmessage.TypeCNAME,
+	"MX":    dnsmessage.TypeMX,
+}
+
+var rcodeNames = map[dnsmessage.RCode]string{
+	dnsmessage.RCodeSuccess:        "NOERROR",
+	dnsmessage.RCodeFormatError:    "FORMERR",
+	dnsmessage.RCodeServerFailure:  "SERVFAIL",
+	dnsmessage.RCodeNameError:      "NXDOMAIN",
+	dnsmessage.RCodeNotImplemented: "NOTIMP",
+	dnsmessage.RCodeRefused:        "REFUSED",
+}
+
+func init() {
+	RegisterTable(dnsTable, DnsMonitor{})
+}
+
+type dnsQuery struct {
+	name     dnsmessage.Name
+	qtype    dnsmessage.Type
+	typeName string
+	expect   []string
+	resolver string
+}
+
+// dnsCollector 直接向配置的解析服务器发送查询，不经过系统缓存，用于发现解析服务器故障
+type dnsCollector struct {
+	queries []dnsQuery
+	timeout time.Duration
+}
+
+func newDNSCollector(cfg configuration.DNSConfig) (*dnsCollector, error) {
+	c := &dnsCollector{timeout: cfg.Timeout}
+	if c.timeout <= 0 {
+		c.timeout = 5 * time.Second
+	}
+
+	resolvers := cfg.Resolvers
+	if len(resolvers) == 0 && len(cfg.Queries) > 0 {
+		resolvers = systemResolvers()
+	}
+
+	for _, q := range cfg.Queries {
+		if q.Name == "" {
+			return nil, errors.New("dns 查询未配置 name")
+		}
+		typeName := strings.ToUpper(q.Type)
+		if typeName == "" {
+			typeName = "A"
+		}
+		qtype, ok := dnsTypes[typeName]
+		if !ok {
+			return nil, fmt.Errorf("dns 查询 %s 类型无效: %s", q.Name, q.Type)
+		}
+
+		name, err := dnsmessage.NewName(fqdn(q.Name))
+		if err != nil {
+			return nil, fmt.Errorf("dns 查询名称无效 %s: %w", q.Name, err)
+		}
+
+		expect := make([]string, 0, len(q.Expect))
+		for _, answer := range q.Expect {
+			expect = append(expect, normalizeAnswer(answer))
+		}
+		slices.Sort(expect)
+
+		servers := q.Resolvers
+		if len(servers) == 0 {
+			servers = resolvers
+		}
+		for _, server := range servers {
+			if _, _, err := net.SplitHostPort(server); err != nil {
+				server = net.JoinHostPort(server, "53")
+			}
+			c.queries = append(c.queries, dnsQuery{
+				name:     name,
+				qtype:    qtype,
+				typeName: typeName,
+				expect:   expect,
+				resolver: server,
+			})
+		}
+	}
+	return c, nil
+}
+
+func (*dnsCollector) Name() string            { return "dns" }
+func (*dnsCollector) Interval() time.Duration { return 0 }
+
+func (c *dnsCollector) Collect(ctx context.Context) ([]Sample, error) {
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		samples []Sample
+	)
+	for _, q := range c.queries {
+		wg.Add(1)
+		go func(q dnsQuery) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(ctx, c.timeout)
+			defer cancel()
+
+			labels := map[string]string{
+				"query":    strings.TrimSuffix(q.name.String(), ".") + "/" + q.typeName + "@" + q.resolver,
+				"name":     strings.TrimSuffix(q.name.String(), "."),
+				"type":     q.typeName,
+				"resolver": q.resolver,
+			}
+
+			start := time.Now()
+			rcode, answers, err := exchange(ctx, q)
+			latency := time.Since(start)
+
+			// 状态随查询结果变化，不作为标签，否则恢复后的样本属于另一条序列
+			var (
+				result []Sample
+				status string
+			)
+			if err != nil {
+				status = "ERROR"
+				if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) {
+					status = "TIMEOUT"
+				}
+				webLogger.Warn("DNS查询失败", zap.String("query", labels["query"]), zap.Error(err))
+				result = []Sample{
+					{Name: dnsPrefix + "up", Value: 0, Labels: labels},
+					{Name: dnsPrefix + "rcode", Value: -1, Labels: labels},
+					{Name: dnsPrefix + "match", Value: 0, Labels: labels},
+				}
+			} else {
+				status = rcodeNames[rcode]
+				if status == "" {
+					status = rcode.String()
+				}
+				match := q.matches(rcode, answers)
+				webLogger.Info("DNS查询结果", zap.String("query", labels["query"]), zap.String("status", status), zap.Duration("latency", latency), zap.Strings("answers", answers), zap.Bool("match", match))
+				result = []Sample{
+					{Name: dnsPrefix + "up", Value: 1, Labels: labels},
+					{Name: dnsPrefix + "rcode", Value: float64(rcode), Labels: labels},
+					{Name: dnsPrefix + "latency", Value: toMillis(latency), Labels: labels},
+					{Name: dnsPrefix + "answers", Value: float64(len(answers)), Labels: labels},
+					{Name: dnsPrefix + "match", Value: boolValue(match), Labels: labels},
+				}
+			}
+			result[0].Attrs = map[string]string{"status": status}
+
+			mu.Lock()
+			samples = append(samples, result...)
+			mu.Unlock()
+		}(q)
+	}
+	wg.Wait()
+
+	return samples, nil
+}
+
+// matches 未配置期望值时只要求 NOERROR 且有应答，否则应答集合需与期望集合完全一致
+func (q dnsQuery) matches(rcode dnsmessage.RCode, answers []string) bool {
+	if rcode != dnsmessage.RCodeSuccess || len(answers) == 0 {
+		return false
+	}
+	if len(q.expect) == 0 {
+		return true
+	}
+	got := slices.Clone(answers)
+	slices.Sort(got)
+	return slices.Equal(slices.Compact(got), slices.Compact(slices.Clone(q.expect)))
+}
+
+// exchange 通过 UDP 发送查询，应答被截断时改用 TCP 重试
+func exchange(ctx context.Context, q dnsQuery) (dnsmessage.RCode, []string, error) {
+	id := uint16(rand.Uint32())
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: id, RecursionDesired: true})
+	builder.EnableCompression()
+	_ = builder.StartQuestions()
+	_ = builder.Question(dnsmessage.Question{Name: q.name, Type: q.qtype, Class: dnsmessage.ClassINET})
+	query, err := builder.Finish()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	resp, err := exchangeUDP(ctx, q.resolver, query)
+	if err != nil {
+		return 0, nil, err
+	}
+	msg, err := parseResponse(resp, id)
+	if err != nil {
+		return 0, nil, err
+	}
+	if msg.Truncated {
+		if resp, err = exchangeTCP(ctx, q.resolver, query); err != nil {
+			return 0, nil, err
+		}
+		if msg, err = parseResponse(resp, id); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	return msg.RCode, answersOf(msg, q.qtype), nil
+}
+
+func exchangeUDP(ctx context.Context, server string, query []byte) ([]byte, error) {
+	conn, err := (&net.Dialer{}).DialContext(ctx, "udp", server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if _, err = conn.Write(query); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 65535)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+func exchangeTCP(ctx context.Context, server string, query []byte) ([]byte, error) {
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	// TCP 报文前两个字节为长度
+	packet := binary.BigEndian.AppendUint16(nil, uint16(len(query)))
+	if _, err = conn.Write(append(packet, query...)); err != nil {
+		return nil, err
+	}
+	var length [2]byte
+	if _, err = io.ReadFull(conn, length[:]); err != nil {
+		return nil, err
+	}
+	resp := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err = io.ReadFull(conn, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func parseResponse(resp []byte, id uint16) (*dnsmessage.Message, error) {
+	var msg dnsmessage.Message
+	if err := msg.Unpack(resp); err != nil {
+		return nil, err
+	}
+	if msg.ID != id || !msg.Response {
+		return nil, errors.New("应答与查询不匹配")
+	}
+	return &msg, nil
+}
+
+// answersOf 提取与查询类型一致的应答，MX 只取邮件服务器名称
+func answersOf(msg *dnsmessage.Message, qtype dnsmessage.Type) []string {
+	var answers []string
+	for _, rr := range msg.Answers {
+		if rr.Header.Type != qtype {
+			continue
+		}
+		switch body := rr.Body.(type) {
+		case *dnsmessage.AResource:
+			answers = append(answers, net.IP(body.A[:]).String())
+		case *dnsmessage.AAAAResource:
+			answers = append(answers, net.IP(body.AAAA[:]).String())
+		case *dnsmessage.CNAMEResource:
+			answers = append(answers, normalizeAnswer(body.CNAME.String()))
+		case *dnsmessage.MXResource:
+			answers = append(answers, normalizeAnswer(body.MX.String()))
+		}
+	}
+	return answers
+}
+
+// normalizeAnswer 统一为小写并去掉末尾的点，IP 地址转换为标准格式
+func normalizeAnswer(answer string) string {
+	if ip := net.ParseIP(answer); ip != nil {
+		return ip.String()
+	}
+	return strings.TrimSuffix(strings.ToLower(answer), ".")
+}
+
+func fqdn(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
+
+// systemResolvers 读取 /etc/resolv.conf 中的 nameserver，读取失败时使用本机
+func systemResolvers() []string {
+	f, err := os.Open(resolvConf)
+	if err != nil {
+		return []string{"127.0.0.1"}
+	}
+	defer f.Close()
+
+	var servers []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			servers = append(servers, fields[1])
+		}
+	}
+	if len(servers) == 0 {
+		return []string{"127.0.0.1"}
+	}
+	return servers
+}
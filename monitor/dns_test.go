@@ -0,0 +1,158 @@
+package monitor
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/solvewer/server-monitor/configuration"
+	"go.uber.org/zap"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// fakeResolver 本地 UDP 解析服务器，按查询名返回固定应答
+func fakeResolver(t *testing.T, answer func(q dnsmessage.Question, b *dnsmessage.Builder) dnsmessage.RCode) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("无法监听 UDP: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var msg dnsmessage.Message
+			if err := msg.Unpack(buf[:n]); err != nil || len(msg.Questions) != 1 {
+				continue
+			}
+			q := msg.Questions[0]
+
+			// 先写入应答以确定 rcode，再按 rcode 重建报文头
+			answers := dnsmessage.NewBuilder(nil, dnsmessage.Header{})
+			_ = answers.StartAnswers()
+			rcode := answer(q, &answers)
+
+			b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: msg.ID, Response: true, RCode: rcode})
+			_ = b.StartQuestions()
+			_ = b.Question(q)
+			_ = b.StartAnswers()
+			answer(q, &b)
+			resp, err := b.Finish()
+			if err != nil {
+				continue
+			}
+			_, _ = conn.WriteTo(resp, addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestDNSExchange(t *testing.T) {
+	server := fakeResolver(t, func(q dnsmessage.Question, b *dnsmessage.Builder) dnsmessage.RCode {
+		header := dnsmessage.ResourceHeader{Name: q.Name, Class: dnsmessage.ClassINET, TTL: 60}
+		switch q.Name.String() {
+		case "api.example.com.":
+			_ = b.CNAMEResource(header, dnsmessage.CNAMEResource{CNAME: dnsmessage.MustNewName("Edge.Example.COM.")})
+			_ = b.AResource(header, dnsmessage.AResource{A: [4]byte{203, 0, 113, 11}})
+			_ = b.AResource(header, dnsmessage.AResource{A: [4]byte{203, 0, 113, 10}})
+		case "example.com.":
+			_ = b.MXResource(header, dnsmessage.MXResource{Pref: 10, MX: dnsmessage.MustNewName("MX1.example.com.")})
+		default:
+			return dnsmessage.RCodeNameError
+		}
+		return dnsmessage.RCodeSuccess
+	})
+
+	tests := []struct {
+		name    string
+		qtype   dnsmessage.Type
+		expect  []string
+		rcode   dnsmessage.RCode
+		answers []string
+		match   bool
+	}{
+		{"api.example.com.", dnsmessage.TypeA, []string{"203.0.113.10", "203.0.113.11"}, dnsmessage.RCodeSuccess, []string{"203.0.113.11", "203.0.113.10"}, true},
+		{"api.example.com.", dnsmessage.TypeA, []string{"203.0.113.10"}, dnsmessage.RCodeSuccess, []string{"203.0.113.11", "203.0.113.10"}, false},
+		{"api.example.com.", dnsmessage.TypeCNAME, nil, dnsmessage.RCodeSuccess, []string{"edge.example.com"}, true},
+		{"example.com.", dnsmessage.TypeMX, []string{"mx1.example.com"}, dnsmessage.RCodeSuccess, []string{"mx1.example.com"}, true},
+		{"missing.example.com.", dnsmessage.TypeA, nil, dnsmessage.RCodeNameError, nil, false},
+	}
+	for _, tt := range tests {
+		q := dnsQuery{name: dnsmessage.MustNewName(tt.name), qtype: tt.qtype, expect: tt.expect, resolver: server}
+		rcode, answers, err := exchange(context.Background(), q)
+		if err != nil {
+			t.Fatalf("%s %v: %v", tt.name, tt.qtype, err)
+		}
+		if rcode != tt.rcode {
+			t.Errorf("%s %v: rcode = %v, want %v", tt.name, tt.qtype, rcode, tt.rcode)
+		}
+		if len(answers) != len(tt.answers) {
+			t.Fatalf("%s %v: answers = %v, want %v", tt.name, tt.qtype, answers, tt.answers)
+		}
+		for i := range answers {
+			if answers[i] != tt.answers[i] {
+				t.Errorf("%s %v: answers = %v, want %v", tt.name, tt.qtype, answers, tt.answers)
+			}
+		}
+		if got := q.matches(rcode, answers); got != tt.match {
+			t.Errorf("%s %v: matches = %v, want %v", tt.name, tt.qtype, got, tt.match)
+		}
+	}
+}
+
+func TestNormalizeAnswer(t *testing.T) {
+	tests := map[string]string{
+		"203.0.113.10":       "203.0.113.10",
+		"2001:DB8::0001":     "2001:db8::1",
+		"MX1.Example.COM.":   "mx1.example.com",
+		"edge.example.com":   "edge.example.com",
+		"::ffff:203.0.113.1": "203.0.113.1",
+	}
+	for in, want := range tests {
+		if got := normalizeAnswer(in); got != want {
+			t.Errorf("normalizeAnswer(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// 解析状态不属于序列标签，失败与恢复后的样本需属于同一条序列
+func TestDNSStatusNotLabel(t *testing.T) {
+	webLogger = zap.NewNop()
+	server := fakeResolver(t, func(q dnsmessage.Question, b *dnsmessage.Builder) dnsmessage.RCode {
+		return dnsmessage.RCodeServerFailure
+	})
+
+	c, err := newDNSCollector(configuration.DNSConfig{
+		Resolvers: []string{server},
+		Queries:   []configuration.DNSQueryConfig{{Name: "api.example.com"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples, err := c.Collect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) == 0 {
+		t.Fatal("没有样本")
+	}
+	for _, s := range samples {
+		if _, ok := s.Labels["status"]; ok {
+			t.Errorf("%s 带有 status 标签: %v", s.Name, s.Labels)
+		}
+	}
+
+	records := childRecords[DnsMonitor](dnsTable, dnsPrefix, "query", samples, 0, time.Now())
+	if len(records) != 1 {
+		t.Fatalf("records = %d, want 1", len(records))
+	}
+	if status := records[0].Value.(*DnsMonitor).Status; status != "SERVFAIL" {
+		t.Errorf("Status = %q, want SERVFAIL", status)
+	}
+}
@@ -0,0 +1,30 @@
+package monitor
+
+import "path/filepath"
+
+// nameFilter 按 glob 模式过滤名称：先排除，再在 include 非空时要求命中其一
+type nameFilter struct {
+	include []string
+	exclude []string
+}
+
+func newNameFilter(include, exclude []string) nameFilter {
+	return nameFilter{include: include, exclude: exclude}
+}
+
+func (f nameFilter) match(name string) bool {
+	for _, pattern := range f.exclude {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, pattern := range f.include {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,235 @@
+package monitor
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/solvewer/server-monitor/util"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// InnodbMonitor InnoDB 引擎指标，每个实例一行，写入子表 server_monitor_mysql_innodb；
+// 带 rate 后缀的列为每秒速率
+type InnodbMonitor struct {
+	Node                   int       `gorm:"column:node;primaryKey"`
+	Instance               string    `gorm:"column:instance;primaryKey"`
+	RowsReadRate           float64   `gorm:"column:rows_read_rate"`
+	RowsInsertedRate       float64   `gorm:"column:rows_inserted_rate"`
+	RowsUpdatedRate        float64   `gorm:"column:rows_updated_rate"`
+	RowsDeletedRate        float64   `gorm:"column:rows_deleted_rate"`
+	RowLockWaitsRate       float64   `gorm:"column:row_lock_waits_rate"`
+	RowLockTimeAvg         float64   `gorm:"column:row_lock_time_avg"` // 本周期平均每次行锁等待时间，毫秒
+	RowLockCurrentWaits    int       `gorm:"column:row_lock_current_waits"`
+	Deadlocks              int       `gorm:"column:deadlocks"` // 本周期新增死锁数
+	BufferPoolPagesTotal   int64     `gorm:"column:buffer_pool_pages_total"`
+	BufferPoolPagesData    int64     `gorm:"column:buffer_pool_pages_data"`
+	BufferPoolPagesDirty   int64     `gorm:"column:buffer_pool_pages_dirty"`
+	BufferPoolPagesFree    int64     `gorm:"column:buffer_pool_pages_free"`
+	BufferPoolDirtyRatio   float64   `gorm:"column:buffer_pool_dirty_ratio"` // 脏页占比 %
+	BufferPoolReadReqRate  float64   `gorm:"column:buffer_pool_read_requests_rate"`
+	BufferPoolReadsRate    float64   `gorm:"column:buffer_pool_reads_rate"` // 未命中缓存池、从磁盘读取的次数
+	BufferPoolWriteReqRate float64   `gorm:"column:buffer_pool_write_requests_rate"`
+	PagesFlushedRate       float64   `gorm:"column:pages_flushed_rate"`
+	BufferPoolWaitFreeRate float64   `gorm:"column:buffer_pool_wait_free_rate"`
+	DataReadRate           float64   `gorm:"column:data_read_rate"` // 字节/秒
+	DataWrittenRate        float64   `gorm:"column:data_written_rate"`
+	LogWritesRate          float64   `gorm:"column:log_writes_rate"`
+	LogWriteRequestsRate   float64   `gorm:"column:log_write_requests_rate"`
+	LogWrittenRate         float64   `gorm:"column:log_written_rate"` // 重做日志写入字节/秒
+	LogWaitsRate           float64   `gorm:"column:log_waits_rate"`
+	LsnRate                float64   `gorm:"column:lsn_rate"`       // LSN 增长，字节/秒
+	CheckpointAge          int64     `gorm:"column:checkpoint_age"` // 未做检查点的重做日志字节数
+	HistoryListLength      int64     `gorm:"column:history_list_length"`
+	CreatedAt              time.Time `gorm:"column:created_at;primaryKey"`
+}
+
+const (
+	innodbTable  = "server_monitor_mysql_innodb"
+	innodbPrefix = "innodb_"
+)
+
+// innodbRates 需要计算每秒速率的累计状态变量与对应的列
+var innodbRates = map[string]string{
+	"Innodb_rows_read":                  "rows_read_rate",
+	"Innodb_rows_inserted":              "rows_inserted_rate",
+	"Innodb_rows_updated":               "rows_updated_rate",
+	"Innodb_rows_deleted":               "rows_deleted_rate",
+	"Innodb_row_lock_waits":             "row_lock_waits_rate",
+	"Innodb_buffer_pool_read_requests":  "buffer_pool_read_requests_rate",
+	"Innodb_buffer_pool_reads":          "buffer_pool_reads_rate",
+	"Innodb_buffer_pool_write_requests": "buffer_pool_write_requests_rate",
+	"Innodb_buffer_pool_pages_flushed":  "pages_flushed_rate",
+	"Innodb_buffer_pool_wait_free":      "buffer_pool_wait_free_rate",
+	"Innodb_data_read":                  "data_read_rate",
+	"Innodb_data_written":               "data_written_rate",
+	"Innodb_log_writes":                 "log_writes_rate",
+	"Innodb_log_write_requests":         "log_write_requests_rate",
+	"Innodb_os_log_written":             "log_written_rate",
+	"Innodb_log_waits":                  "log_waits_rate",
+	"Log sequence number":               "lsn_rate",
+}
+
+// innodbGauges 直接记录当前值的状态变量
+var innodbGauges = map[string]string{
+	"Innodb_row_lock_current_waits":  "row_lock_current_waits",
+	"Innodb_buffer_pool_pages_total": "buffer_pool_pages_total",
+	"Innodb_buffer_pool_pages_data":  "buffer_pool_pages_data",
+	"Innodb_buffer_pool_pages_dirty": "buffer_pool_pages_dirty",
+	"Innodb_buffer_pool_pages_free":  "buffer_pool_pages_free",
+}
+
+var (
+	historyListRegexp = regexp.MustCompile(`History list length\s+(\d+)`)
+	lsnRegexp         = regexp.MustCompile(`Log sequence number\s+(\d+)`)
+	checkpointRegexp  = regexp.MustCompile(`Last checkpoint at\s+(\d+)`)
+)
+
+func init() {
+	RegisterTable(innodbTable, InnodbMonitor{})
+}
+
+// innodbCollector 采集 InnoDB 状态变量与 SHOW ENGINE INNODB STATUS 中的
+// 历史链表长度、LSN 与检查点，累计计数通过共享的计数状态换算为每秒速率
+type innodbCollector struct {
+	db       *gorm.DB
+	status   *statusCache
+	innodb   *innodbStatusCache
+	counters counterScope
+	logger   *zap.Logger
+}
+
+func (*innodbCollector) Name() string            { return "mysql_innodb" }
+func (*innodbCollector) Interval() time.Duration { return 0 }
+
+func (c *innodbCollector) Collect(ctx context.Context) ([]Sample, error) {
+	all, _, now, err := c.status.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status := make(map[string]float64)
+	for name, v := range all {
+		if strings.HasPrefix(name, "Innodb_") {
+			status[name] = v
+		}
+	}
+
+	// SHOW ENGINE INNODB STATUS 需要 PROCESS 权限，失败时只记录日志
+	if text, err := c.innodb.get(ctx); err != nil {
+		c.logger.Warn("读取 InnoDB 状态失败", zap.Error(err))
+	} else {
+		for name, v := range parseInnodbStatus(text) {
+			status[name] = v
+		}
+	}
+
+	// 死锁计数：MySQL 在 INNODB_METRICS 中（默认开启），MariaDB 为 Innodb_deadlocks 状态变量
+	if _, ok := status["Innodb_deadlocks"]; !ok {
+		var deadlocks float64
+		err := c.db.WithContext(ctx).Raw("SELECT `COUNT` FROM information_schema.INNODB_METRICS WHERE NAME = 'lock_deadlocks'").Row().Scan(&deadlocks)
+		if err == nil {
+			status["Innodb_deadlocks"] = deadlocks
+		}
+	}
+
+	var samples []Sample
+	for name, column := range innodbGauges {
+		if v, ok := status[name]; ok {
+			samples = append(samples, gauge(innodbPrefix+column, v))
+		}
+	}
+	if total := status["Innodb_buffer_pool_pages_total"]; total > 0 {
+		samples = append(samples, gauge(innodbPrefix+"buffer_pool_dirty_ratio", util.ToDouble(status["Innodb_buffer_pool_pages_dirty"]*100/total)))
+	}
+	if v, ok := status["History list length"]; ok {
+		samples = append(samples, gauge(innodbPrefix+"history_list_length", v))
+	}
+	if lsn, ok := status["Log sequence number"]; ok {
+		if checkpoint, ok := status["Last checkpoint at"]; ok {
+			samples = append(samples, gauge(innodbPrefix+"checkpoint_age", lsn-checkpoint))
+		}
+	}
+
+	// 首次观测与计数回退（实例重启）时跳过本周期的速率
+	for name, column := range innodbRates {
+		if v, ok := status[name]; ok {
+			if rate, ok := c.counters.rate("innodb:"+name, v, now); ok {
+				samples = append(samples, gauge(innodbPrefix+column, util.ToDouble(rate)))
+			}
+		}
+	}
+
+	lockTime, ok1 := c.counters.delta("innodb:Innodb_row_lock_time", status["Innodb_row_lock_time"], now)
+	waits, ok2 := c.counters.delta("innodb:Innodb_row_lock_waits:avg", status["Innodb_row_lock_waits"], now)
+	if ok1 && ok2 {
+		avg := 0.0
+		if waits > 0 {
+			avg = util.ToDouble(lockTime / waits)
+		}
+		samples = append(samples, gauge(innodbPrefix+"row_lock_time_avg", avg))
+	}
+
+	if v, ok := status["Innodb_deadlocks"]; ok {
+		if deadlocks, ok := c.counters.delta("innodb:Innodb_deadlocks", v, now); ok {
+			samples = append(samples, gauge(innodbPrefix+"deadlocks", deadlocks))
+		}
+	}
+
+	return samples, nil
+}
+
+// parseInnodbStatus 从 SHOW ENGINE INNODB STATUS 中解析历史链表长度、LSN 与检查点，
+// 未找到的项不出现在结果中
+func parseInnodbStatus(text string) map[string]float64 {
+	values := make(map[string]float64, 3)
+	for name, re := range map[string]*regexp.Regexp{
+		"History list length": historyListRegexp,
+		"Log sequence number": lsnRegexp,
+		"Last checkpoint at":  checkpointRegexp,
+	} {
+		if m := re.FindStringSubmatch(text); m != nil {
+			values[name], _ = strconv.ParseFloat(m[1], 64)
+		}
+	}
+	return values
+}
+
+// innodbStatusCache 同一实例的采集器共享的 SHOW ENGINE INNODB STATUS 结果，
+// 与 statusCache 相同，每个周期只查询一次；查询失败也在有效期内共享，避免重复报错
+type innodbStatusCache struct {
+	db *gorm.DB
+
+	mu   sync.Mutex
+	at   time.Time
+	text string
+	err  error
+}
+
+func newInnodbStatusCache(db *gorm.DB) *innodbStatusCache {
+	return &innodbStatusCache{db: db}
+}
+
+func (c *innodbStatusCache) get(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Since(c.at) < statusCacheTTL {
+		return c.text, c.err
+	}
+	c.text, c.err = innodbStatus(c.db.WithContext(ctx))
+	c.at = time.Now()
+	return c.text, c.err
+}
+
+// innodbStatus 返回 SHOW ENGINE INNODB STATUS 的文本
+func innodbStatus(db *gorm.DB) (string, error) {
+	var typ, name, status string
+	if err := db.Raw("SHOW ENGINE INNODB STATUS").Row().Scan(&typ, &name, &status); err != nil {
+		return "", err
+	}
+	return status, nil
+}
@@ -0,0 +1,71 @@
+package monitor
+
+import (
+	"reflect"
+	"testing"
+)
+
+// 5.7 的 TRANSACTIONS 与 LOG 段
+const innodbLog57 = `
+------------
+TRANSACTIONS
+------------
+Trx id counter 421940
+Purge done for trx's n:o < 421939 undo n:o < 0 state: running but idle
+History list length 24
+---
+LOG
+---
+Log sequence number 2611457
+Log flushed up to   2611457
+Pages flushed up to 2611457
+Last checkpoint at  2611448
+0 pending log flushes, 0 pending chkp writes
+10 log i/o's done, 0.00 log i/o's/second
+`
+
+// 8.0 的 LOG 段按列对齐，并新增了 Log buffer/Added dirty pages 等行
+const innodbLog80 = `
+------------
+TRANSACTIONS
+------------
+Trx id counter 10110
+Purge done for trx's n:o < 10108 undo n:o < 0 state: running but idle
+History list length 3
+---
+LOG
+---
+Log sequence number          31499455
+Log buffer assigned up to    31499455
+Log buffer completed up to   31499455
+Log written up to            31499455
+Log flushed up to            31499455
+Added dirty pages up to      31499455
+Pages flushed up to          31499120
+Last checkpoint at           31498000
+Log minimum file id is       9
+Log maximum file id is       9
+`
+
+func TestParseInnodbStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		status string
+		want   map[string]float64
+	}{
+		{"5.7", innodbLog57, map[string]float64{
+			"History list length": 24, "Log sequence number": 2611457, "Last checkpoint at": 2611448,
+		}},
+		{"8.0", innodbLog80, map[string]float64{
+			"History list length": 3, "Log sequence number": 31499455, "Last checkpoint at": 31498000,
+		}},
+		// 只有死锁段时不返回 LOG 相关的值
+		{"5.7 死锁段", innodbStatus57, map[string]float64{}},
+		{"空", "", map[string]float64{}},
+	}
+	for _, tt := range tests {
+		if got := parseInnodbStatus(tt.status); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: parseInnodbStatus = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -2,15 +2,32 @@ package monitor
 
 import (
 	"context"
-	"github.com/shirou/gopsutil/v4/disk"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	driver "github.com/go-sql-driver/mysql"
 	"github.com/solvewer/server-monitor/configuration"
 	"github.com/solvewer/server-monitor/util"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
-	"time"
 )
 
+// MysqlMonitor 每个实例每个周期一行，写入主表 server_monitor_mysql；
+// 未开启 sinks.auto_migrate 时需手动执行：
+//
+//	ALTER TABLE server_monitor_mysql
+//	  ADD COLUMN node bigint NOT NULL DEFAULT 0,
+//	  ADD COLUMN instance varchar(191) NOT NULL DEFAULT '',
+//	  ADD UNIQUE INDEX idx_mysql_node_instance_time (node, instance, created_at);
 type MysqlMonitor struct {
+	Node             int       `gorm:"column:node;not null;default:0;uniqueIndex:idx_mysql_node_instance_time,priority:1"`
+	Instance         string    `gorm:"column:instance;size:191;not null;default:'';uniqueIndex:idx_mysql_node_instance_time,priority:2"`
 	ThreadsConnected int       `gorm:"column:threads_connected"`
 	ThreadsRunning   int       `gorm:"column:threads_running"`
 	Qps              int       `gorm:"column:qps"`
@@ -18,40 +35,184 @@ type MysqlMonitor struct {
 	BufferHitRate    float64   `gorm:"column:buffer_hit_rate"`
 	WriteSpeed       float64   `gorm:"column:write_speed"`
 	ReadSpeed        float64   `gorm:"column:read_speed"`
-	CreatedAt        time.Time `gorm:"column:created_at"`
+	CreatedAt        time.Time `gorm:"column:created_at;uniqueIndex:idx_mysql_node_instance_time,priority:3"`
 }
 
 var (
-	lastQueries     int
-	lastSlowQueries int
-	prevIO          map[string]disk.IOCountersStat
-	mysqlLogger     *zap.Logger
+	mysqlLogger *zap.Logger
+	// mysqlRegistry 本机采集器（磁盘IO），结果只写入本机实例的行
+	mysqlRegistry  = NewRegistry()
+	mysqlInstances []*mysqlInstance
+	mysqlSinks     *Fanout
 )
 
+// mysqlInstance 被监控的 Mysql 实例，每个实例有独立的连接与采集器
+type mysqlInstance struct {
+	name     string
+	local    bool
+	labels   map[string]string // instance 与配置的 tags，附加到该实例的所有样本
+	registry *Registry
+	counters counterScope
+	logger   *zap.Logger
+}
+
+func newMysqlInstance(name string, conn *gorm.DB, local bool, tags map[string]string, rules []killRule) *mysqlInstance {
+	labels := map[string]string{"instance": name}
+	for k, v := range tags {
+		if k != "instance" {
+			labels[k] = v
+		}
+	}
+
+	inst := &mysqlInstance{
+		name:     name,
+		local:    local,
+		labels:   labels,
+		registry: NewRegistry(),
+		counters: counters.scope(name),
+		logger:   mysqlLogger.With(zap.String("instance", name)),
+	}
+	status, innodb := newStatusCache(conn), newInnodbStatusCache(conn)
+	inst.registry.Register(
+		mysqlThreadsCollector{status: status},
+		newMysqlQueriesCollector(status, inst.counters),
+		mysqlBufferCollector{status: status},
+		newStatusCollector(status, inst.counters, config.Mysql.Status),
+		&replicaCollector{db: conn, logger: inst.logger},
+		&innodbCollector{db: conn, status: status, innodb: innodb, counters: inst.counters, logger: inst.logger},
+		&processlistCollector{db: conn, cfg: config.Mysql.Processlist, rules: rules, logger: inst.logger},
+		&digestCollector{db: conn, cfg: config.Mysql.Digest, counters: inst.counters},
+		&tablesCollector{db: conn, cfg: config.Mysql.Tables, local: local, logger: inst.logger},
+		&locksCollector{db: conn, innodb: innodb, logger: inst.logger},
+		&binlogCollector{db: conn, status: status, innodb: innodb, counters: inst.counters, logger: inst.logger},
+	)
+	inst.registry.Configure(config.Collectors.Enabled, config.Collectors.Disabled, config.Collectors.Intervals)
+	return inst
+}
+
+// newMysqlInstances 按配置连接被监控实例，未配置时监控存储库本身
+func newMysqlInstances() ([]*mysqlInstance, error) {
+	rules, err := compileKillRules(config.Mysql.Processlist.Kill)
+	if err != nil {
+		return nil, err
+	}
+	if err = validDigestOrder(config.Mysql.Digest.OrderBy); err != nil {
+		return nil, err
+	}
+
+	if len(config.Mysql.Instances) == 0 {
+		local := isLocalAddr(config.DB.Host)
+		name := net.JoinHostPort(config.DB.Host, strconv.Itoa(config.DB.Port))
+		return []*mysqlInstance{newMysqlInstance(name, db, local, nil, rulesFor(rules, name))}, nil
+	}
+
+	instances := make([]*mysqlInstance, 0, len(config.Mysql.Instances))
+	for _, cfg := range config.Mysql.Instances {
+		dsn, err := driver.ParseDSN(cfg.DSN)
+		if err != nil {
+			return nil, fmt.Errorf("实例 %s 的 dsn 无效: %w", cfg.Name, err)
+		}
+		name := cfg.Name
+		if name == "" {
+			name = dsn.Addr
+		}
+		conn, err := configuration.OpenMysql(cfg.DSN)
+		if err != nil {
+			return nil, fmt.Errorf("连接实例 %s 失败: %w", name, err)
+		}
+
+		local := cfg.Local || dsn.Net == "unix"
+		if host, _, err := net.SplitHostPort(dsn.Addr); err == nil && isLocalAddr(host) {
+			local = true
+		}
+		instances = append(instances, newMysqlInstance(name, conn, local, cfg.Tags, rulesFor(rules, name)))
+	}
+
+	for _, rule := range rules {
+		for _, name := range rule.Instances {
+			if !slices.ContainsFunc(instances, func(inst *mysqlInstance) bool { return inst.name == name }) {
+				return nil, fmt.Errorf("终止规则 %s 的实例 %s 未配置", rule.Name, name)
+			}
+		}
+	}
+	return instances, nil
+}
+
+func isLocalAddr(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// mysqlErrorIs 是否为指定错误码的 MySQL 错误
+func mysqlErrorIs(err error, numbers ...uint16) bool {
+	var mysqlErr *driver.MySQLError
+	return errors.As(err, &mysqlErr) && slices.Contains(numbers, mysqlErr.Number)
+}
+
+// versionBefore 服务器版本（如 8.0.21-log、10.4.32-MariaDB-log）是否早于给定版本，
+// MySQL 与 MariaDB 分别比较
+func versionBefore(version string, mysql, mariadb [3]int) bool {
+	want := mysql
+	if strings.Contains(strings.ToLower(version), "mariadb") {
+		want = mariadb
+	}
+	number, _, _ := strings.Cut(version, "-")
+	parts := strings.SplitN(number, ".", 3)
+	for i := range want {
+		got := 0
+		if i < len(parts) {
+			got, _ = strconv.Atoi(parts[i])
+		}
+		if got != want[i] {
+			return got < want[i]
+		}
+	}
+	return false
+}
+
+func init() {
+	registerMainTable("server_monitor_mysql", MysqlMonitor{})
+}
+
 func StartMysql() {
 	// mysql日志
+	setup()
 	mysqlLogger = configuration.GetLogger(configuration.MysqlLogName)
-	if lastQueries == 0 {
-		lastQueries, _ = GetStatus(db, "Queries")
+
+	mysqlRegistry.Register(newMysqlIOCollector(config.Collectors.Devices))
+	mysqlRegistry.Configure(config.Collectors.Enabled, config.Collectors.Disabled, config.Collectors.Intervals)
+	mysqlLogger.Info("已启用的本机采集器", zap.Strings("collectors", mysqlRegistry.Names()))
+
+	var err error
+	if mysqlInstances, err = newMysqlInstances(); err != nil {
+		mysqlLogger.Error("初始化监控实例失败", zap.Error(err))
+		panic(err)
 	}
-	if lastSlowQueries == 0 {
-		lastSlowQueries, _ = GetStatus(db, "Slow_queries")
+	for _, inst := range mysqlInstances {
+		mysqlLogger.Info("监控实例", zap.String("instance", inst.name), zap.Bool("local", inst.local), zap.Strings("collectors", inst.registry.Names()))
 	}
 
-	prevIO, _ = disk.IOCounters()
+	if mysqlSinks, err = newSinks("mysql", config.Sinks.Enabled, mysqlLogger); err != nil {
+		mysqlLogger.Error("初始化存储失败", zap.Error(err))
+		panic(err)
+	}
+	mysqlLogger.Info("已启用的存储", zap.Strings("sinks", mysqlSinks.Names()))
 
-	// Step 1: 计算距离下一个整分钟的时间
+	// Step 1: 计算距离下一个统计周期的时间
 	now := time.Now()
 	mysqlLogger.Info("Mysql数据库开始监控时间", zap.Time("开始监控", now))
 
-	next := now.Truncate(time.Minute).Add(time.Minute + time.Second*30)
-	time.Sleep(time.Until(next)) // 等待直到下一个30秒时刻
+	next := now.Truncate(config.Interval).Add(config.Interval + config.Interval/2)
+	time.Sleep(time.Until(next)) // 等待直到下一个周期的中间时刻（默认为下一分钟的30秒）
 
 	mysqlLogger.Info("开始统计时间", zap.Time("开始统计", time.Now()))
 	mysqlRun(next)
 	mysqlLogger.Info("统计结束时间", zap.Time("结束统计", time.Now()))
 
-	ticker := time.NewTicker(time.Minute)
+	ticker := time.NewTicker(config.Interval)
 	defer ticker.Stop()
 
 	for now = range ticker.C {
@@ -78,66 +239,169 @@ func GetStatus(db *gorm.DB, item string) (int, error) {
 }
 
 func mysqlRun(t time.Time) {
-	mysqlMonitor := mysqlCalc(t)
+	snapshot := mysqlCalc(t)
+	if err := mysqlSinks.Write(context.Background(), snapshot); err != nil {
+		mysqlLogger.Error("新增数据失败", zap.Error(err))
+	}
+}
+
+// mysqlCalc 并发采集所有实例，每个实例一行主表数据，样本附加 instance 标签
+func mysqlCalc(t time.Time) *Snapshot {
+	createdAt := t.Truncate(config.Interval)
+	hostSamples := mysqlRegistry.Collect(context.Background(), t, mysqlLogger)
 
-	ctx := context.Background()
-	err := gorm.G[MysqlMonitor](db).Table("server_monitor_mysql").Create(ctx, mysqlMonitor)
+	results := make([][]Sample, len(mysqlInstances))
+	var wg sync.WaitGroup
+	for i, inst := range mysqlInstances {
+		wg.Add(1)
+		go func(i int, inst *mysqlInstance) {
+			defer wg.Done()
+			results[i] = inst.registry.Collect(context.Background(), t, inst.logger)
+		}(i, inst)
+	}
+	wg.Wait()
+
+	samples := hostSamples
+	var records []Record
+	for i, inst := range mysqlInstances {
+		mysqlMonitor := &MysqlMonitor{Node: config.Node, Instance: inst.name, CreatedAt: createdAt}
+		fill(mysqlMonitor, "", unlabelled(results[i]), nil)
+		if inst.local {
+			fill(mysqlMonitor, "", hostSamples, nil)
+		}
+		records = append(records, Record{Table: "server_monitor_mysql", Value: mysqlMonitor})
+
+		tagged := withLabels(results[i], inst.labels)
+		records = append(records, childRecords[ReplicaMonitor](replicaTable, replicaPrefix, "channel", tagged, config.Node, createdAt)...)
+		records = append(records, statusRecords(tagged, config.Node, createdAt)...)
+		records = append(records, childRecords[InnodbMonitor](innodbTable, innodbPrefix, "instance", tagged, config.Node, createdAt)...)
+		records = append(records, childRecords[ProcesslistMonitor](processlistTable, processlistPrefix, "process", tagged, config.Node, createdAt)...)
+		records = append(records, childRecords[DigestMonitor](digestTable, digestPrefix, "statement", tagged, config.Node, createdAt)...)
+		records = append(records, childRecords[TableSizeMonitor](tableSizeTable, tableSizePrefix, "table", tagged, config.Node, createdAt)...)
+		records = append(records, childRecords[SchemaSizeMonitor](schemaSizeTable, schemaSizePrefix, "schema_name", tagged, config.Node, createdAt)...)
+		records = append(records, childRecords[DatadirMonitor](datadirTable, datadirPrefix, "instance", tagged, config.Node, createdAt)...)
+		records = append(records, childRecords[DeadlockMonitor](deadlockTable, deadlockPrefix, "txn", tagged, config.Node, createdAt)...)
+		records = append(records, childRecords[LogMonitor](logTable, logPrefix, "instance", tagged, config.Node, createdAt)...)
+		records = append(records, childRecords[LockWaitMonitor](lockWaitTable, lockWaitPrefix, "wait", tagged, config.Node, createdAt)...)
+		samples = append(samples, tagged...)
+	}
+
+	return &Snapshot{
+		Source:  "mysql",
+		Node:    config.Node,
+		Time:    createdAt,
+		Samples: samples,
+		Records: records,
+	}
+}
+
+// 当前连接数、活跃连接数
+type mysqlThreadsCollector struct {
+	status *statusCache
+}
+
+func (mysqlThreadsCollector) Name() string            { return "mysql_threads" }
+func (mysqlThreadsCollector) Interval() time.Duration { return 0 }
+
+func (c mysqlThreadsCollector) Collect(ctx context.Context) ([]Sample, error) {
+	status, _, _, err := c.status.get(ctx)
 	if err != nil {
-		mysqlLogger.Error("新增数据失败", zap.Error(err))
+		return nil, err
 	}
-	sql := db.ToSQL(func(tx *gorm.DB) *gorm.DB {
-		return tx.Model(&MysqlMonitor{}).Create(mysqlMonitor)
-	})
 
-	mysqlLogger.Info("sql及插入的行数",
-		zap.String("sql", sql),
-		zap.Int64("rows", db.RowsAffected),
-	)
+	return []Sample{
+		gauge("threads_connected", status["Threads_connected"]),
+		gauge("threads_running", status["Threads_running"]),
+	}, nil
+}
+
+// QPS、本周期慢查询数量
+type mysqlQueriesCollector struct {
+	status   *statusCache
+	counters counterScope
 }
 
-func mysqlCalc(t time.Time) *MysqlMonitor {
-	mysqlMonitor := new(MysqlMonitor)
+// newMysqlQueriesCollector 启动时记录基准值，第一个周期即可计算
+func newMysqlQueriesCollector(status *statusCache, counters counterScope) *mysqlQueriesCollector {
+	if values, _, at, err := status.get(context.Background()); err == nil {
+		counters.observe("queries:Queries", values["Queries"], at, counter64)
+		counters.observe("queries:Slow_queries", values["Slow_queries"], at, counter64)
+	}
+	return &mysqlQueriesCollector{status: status, counters: counters}
+}
 
-	// 当前连接数
-	mysqlMonitor.ThreadsConnected, _ = GetStatus(db, "Threads_connected")
+func (*mysqlQueriesCollector) Name() string            { return "mysql_queries" }
+func (*mysqlQueriesCollector) Interval() time.Duration { return 0 }
 
-	// 活跃连接数
-	mysqlMonitor.ThreadsRunning, _ = GetStatus(db, "Threads_running")
+func (c *mysqlQueriesCollector) Collect(ctx context.Context) ([]Sample, error) {
+	status, _, at, err := c.status.get(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	// QPS
-	queries, _ := GetStatus(db, "Queries")
-	mysqlMonitor.Qps = (queries - lastQueries) / 60
-	lastQueries = queries
+	// 实例重启后计数回退，本周期不输出
+	var samples []Sample
+	if qps, ok := c.counters.rate("queries:Queries", status["Queries"], at); ok {
+		samples = append(samples, gauge("qps", util.ToDouble(qps)))
+	}
+	if slow, ok := c.counters.delta("queries:Slow_queries", status["Slow_queries"], at); ok {
+		samples = append(samples, gauge("slow_queries", slow))
+	}
+	return samples, nil
+}
 
-	// 慢查询数量
-	slowQueries, _ := GetStatus(db, "Slow_queries")
-	mysqlMonitor.SlowQueries = slowQueries - lastSlowQueries
-	lastSlowQueries = slowQueries
+// 缓存池命中率
+type mysqlBufferCollector struct {
+	status *statusCache
+}
 
+func (mysqlBufferCollector) Name() string            { return "mysql_buffer" }
+func (mysqlBufferCollector) Interval() time.Duration { return 0 }
+
+func (c mysqlBufferCollector) Collect(ctx context.Context) ([]Sample, error) {
+	status, _, _, err := c.status.get(ctx)
+	if err != nil {
+		return nil, err
+	}
 	// 请求缓存池数
-	readReq, _ := GetStatus(db, "Innodb_buffer_pool_read_requests")
+	readReq := status["Innodb_buffer_pool_read_requests"]
 	// 读取缓存池
-	reads, _ := GetStatus(db, "Innodb_buffer_pool_reads")
-	if readReq > 0 {
-		mysqlMonitor.BufferHitRate = util.ToDouble(float64(readReq-reads) * 100 / float64(readReq))
+	reads := status["Innodb_buffer_pool_reads"]
+	if readReq <= 0 {
+		return nil, nil
 	}
 
-	// 磁盘IO
-	currIO, _ := disk.IOCounters()
-	for device, curr := range currIO {
-		prev, ok := prevIO[device]
-		if !ok {
-			continue
-		}
+	return []Sample{gauge("buffer_hit_rate", util.ToDouble((readReq-reads)*100/readReq))}, nil
+}
+
+// 磁盘IO：所有磁盘（不含分区）合计的读写速率，MB/s
+type mysqlIOCollector struct {
+	sampler *diskIOSampler
+}
 
-		readBytes := curr.ReadBytes - prev.ReadBytes
-		writeBytes := curr.WriteBytes - prev.WriteBytes
+func newMysqlIOCollector(cfg configuration.DevicesConfig) *mysqlIOCollector {
+	c := &mysqlIOCollector{sampler: newDiskIOSampler(cfg, "mysql_io")}
+	_, _ = c.sampler.sample(context.Background())
+	return c
+}
+
+func (*mysqlIOCollector) Name() string            { return "mysql_io" }
+func (*mysqlIOCollector) Interval() time.Duration { return 0 }
+
+func (c *mysqlIOCollector) Collect(ctx context.Context) ([]Sample, error) {
+	rates, err := c.sampler.sample(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-		mysqlMonitor.WriteSpeed = util.ToDouble(util.ToMbFloat(readBytes / 60))
-		mysqlMonitor.ReadSpeed = util.ToDouble(util.ToMbFloat(writeBytes / 60))
+	var readBytes, writeBytes float64
+	for _, r := range wholeDisks(rates) {
+		readBytes += r.readBytesRate
+		writeBytes += r.writeBytesRate
 	}
-	prevIO = currIO
 
-	mysqlMonitor.CreatedAt = t.Truncate(time.Minute)
-	return mysqlMonitor
+	return []Sample{
+		gauge("read_speed", util.ToDouble(readBytes/1024/1024)),
+		gauge("write_speed", util.ToDouble(writeBytes/1024/1024)),
+	}, nil
 }
@@ -0,0 +1,30 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	driver "github.com/go-sql-driver/mysql"
+)
+
+func TestMysqlErrorIs(t *testing.T) {
+	missing := &driver.MySQLError{Number: 1146, Message: "Table 'performance_schema.global_status' doesn't exist"}
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{missing, true},
+		{fmt.Errorf("查询失败: %w", missing), true},
+		{&driver.MySQLError{Number: 1142, Message: "SELECT command denied"}, true},
+		{&driver.MySQLError{Number: 2013, Message: "Lost connection"}, false},
+		{driver.ErrInvalidConn, false},
+		{errors.New("context deadline exceeded"), false},
+	}
+	for _, tt := range tests {
+		if got := mysqlErrorIs(tt.err, 1044, 1142, 1146, 1227); got != tt.want {
+			t.Errorf("mysqlErrorIs(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package monitor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/net"
+	"github.com/solvewer/server-monitor/configuration"
+	"github.com/solvewer/server-monitor/util"
+	"go.uber.org/zap"
+)
+
+// NetMonitor 各网卡每秒的收发速率，写入子表 server_monitor_net
+type NetMonitor struct {
+	Node            int       `gorm:"column:node;primaryKey"`
+	Interface       string    `gorm:"column:interface;primaryKey"`
+	RecvBytesRate   float64   `gorm:"column:recv_bytes_rate"` // 字节/秒
+	SentBytesRate   float64   `gorm:"column:sent_bytes_rate"`
+	RecvPacketsRate float64   `gorm:"column:recv_packets_rate"` // 包/秒
+	SentPacketsRate float64   `gorm:"column:sent_packets_rate"`
+	ErrInRate       float64   `gorm:"column:err_in_rate"`
+	ErrOutRate      float64   `gorm:"column:err_out_rate"`
+	DropInRate      float64   `gorm:"column:drop_in_rate"`
+	DropOutRate     float64   `gorm:"column:drop_out_rate"`
+	CreatedAt       time.Time `gorm:"column:created_at;primaryKey"`
+}
+
+const (
+	netTable  = "server_monitor_net"
+	netPrefix = "net_"
+)
+
+// sysNetDir 网卡在 sysfs 中的目录
+var sysNetDir = "/sys/class/net"
+
+// defaultExcludeInterfaces 未配置 exclude 时排除回环与容器虚拟网卡
+var defaultExcludeInterfaces = []string{"lo", "docker*", "veth*", "br-*", "virbr*", "cni*", "flannel*"}
+
+func init() {
+	RegisterTable(netTable, NetMonitor{})
+}
+
+// netCollector 按网卡采集累计计数，以两次采样的实际间隔计算每秒速率；
+// 同时输出所有选中网卡在两次采样之间的合计收发量（MB）写入主表 receive_speed/sent_speed，
+// 与原有的每周期流量含义保持一致
+type netCollector struct {
+	interfaces nameFilter
+	counters   counterScope
+}
+
+func newNetCollector(cfg configuration.InterfacesConfig) *netCollector {
+	exclude := cfg.Exclude
+	if exclude == nil {
+		exclude = defaultExcludeInterfaces
+	}
+	return &netCollector{
+		interfaces: newNameFilter(cfg.Include, exclude),
+		counters:   counters.scope("net"),
+	}
+}
+
+// linkWidths 按网卡协商速率（/sys/class/net/<网卡>/speed，Mbit/s）确定字节与包计数的回绕上限，
+// 包速率按最小以太网帧（含前导码与帧间隔共84字节）估算；虚拟网卡等读不到速率时回退一律视为重置
+func linkWidths(name string) (counterWidth, counterWidth) {
+	data, err := os.ReadFile(filepath.Join(sysNetDir, name, "speed"))
+	if err != nil {
+		return counter32(0), counter32(0)
+	}
+	mbps, err := strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
+	if err != nil || mbps <= 0 {
+		return counter32(0), counter32(0)
+	}
+	bytesRate := mbps * 1e6 / 8
+	return counter32(bytesRate), counter32(bytesRate / 84)
+}
+
+func (*netCollector) Name() string            { return "net" }
+func (*netCollector) Interval() time.Duration { return 0 }
+
+func (c *netCollector) Collect(ctx context.Context) ([]Sample, error) {
+	stats, err := net.IOCountersWithContext(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+
+	var (
+		samples   []Sample
+		totalRecv float64
+		totalSent float64
+		computed  bool
+	)
+	for _, stat := range stats {
+		if !c.interfaces.match(stat.Name) {
+			continue
+		}
+
+		values := map[string]uint64{
+			"recv_bytes_rate":   stat.BytesRecv,
+			"sent_bytes_rate":   stat.BytesSent,
+			"recv_packets_rate": stat.PacketsRecv,
+			"sent_packets_rate": stat.PacketsSent,
+			"err_in_rate":       stat.Errin,
+			"err_out_rate":      stat.Errout,
+			"drop_in_rate":      stat.Dropin,
+			"drop_out_rate":     stat.Dropout,
+		}
+		byteWidth, packetWidth := linkWidths(stat.Name)
+
+		// 任一计数重置或没有基准值时整块网卡本周期都不输出，避免同一行中部分计数缺失
+		deltas := make(map[string]float64, len(values))
+		var seconds float64
+		valid, reset := true, false
+		for name, value := range values {
+			width := packetWidth
+			if name == "recv_bytes_rate" || name == "sent_bytes_rate" {
+				width = byteWidth
+			}
+			delta, elapsed, status := c.counters.observe(stat.Name+":"+name, float64(value), now, width)
+			if status != counterOK {
+				valid = false
+				reset = reset || status == counterReset
+				continue
+			}
+			deltas[name], seconds = delta, elapsed
+		}
+		if reset {
+			// 计数器被重置（网卡重建、驱动重载），本周期不输出该网卡
+			webLogger.Warn("网卡计数器重置，跳过本周期", zap.String("interface", stat.Name))
+		}
+		if !valid {
+			continue
+		}
+
+		labels := map[string]string{"interface": stat.Name}
+		for name, delta := range deltas {
+			samples = append(samples, Sample{Name: netPrefix + name, Value: util.ToDouble(delta / seconds), Labels: labels})
+		}
+		totalRecv += deltas["recv_bytes_rate"]
+		totalSent += deltas["sent_bytes_rate"]
+		computed = true
+	}
+
+	// 首次采样没有基准值，不输出合计流量
+	if !computed {
+		return samples, nil
+	}
+	receiveSpeed := util.ToDouble(totalRecv / 1024 / 1024)
+	sentSpeed := util.ToDouble(totalSent / 1024 / 1024)
+	webLogger.Info("网络流量", zap.Float64("ReceiveSpeed", receiveSpeed), zap.Float64("SentSpeed", sentSpeed))
+
+	return append(samples, gauge("receive_speed", receiveSpeed), gauge("sent_speed", sentSpeed)), nil
+}
@@ -0,0 +1,145 @@
+package monitor
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-ping/ping"
+	"github.com/solvewer/server-monitor/configuration"
+	"github.com/solvewer/server-monitor/util"
+	"go.uber.org/zap"
+)
+
+// PingMonitor 各探测目标的 ICMP 结果，写入子表 server_monitor_ping
+type PingMonitor struct {
+	Node        int       `gorm:"column:node;primaryKey"`
+	Target      string    `gorm:"column:target;primaryKey"`
+	Host        string    `gorm:"column:host"`
+	Up          bool      `gorm:"column:up"`      // 至少收到一个响应
+	MinRtt      float64   `gorm:"column:min_rtt"` // 毫秒
+	AvgRtt      float64   `gorm:"column:avg_rtt"`
+	MaxRtt      float64   `gorm:"column:max_rtt"`
+	StddevRtt   float64   `gorm:"column:stddev_rtt"`
+	PacketLoss  float64   `gorm:"column:packet_loss"` // %
+	PacketsSent int       `gorm:"column:packets_sent"`
+	PacketsRecv int       `gorm:"column:packets_recv"`
+	CreatedAt   time.Time `gorm:"column:created_at;primaryKey"`
+}
+
+const (
+	pingTable  = "server_monitor_ping"
+	pingPrefix = "ping_"
+)
+
+func init() {
+	RegisterTable(pingTable, PingMonitor{})
+}
+
+// pingCollector 并发探测配置的所有目标；第一个目标的结果同时写入主表 avg_rtt/packet_loss
+type pingCollector struct {
+	cfg configuration.PingConfig
+}
+
+func newPingCollector(cfg configuration.PingConfig) *pingCollector {
+	if len(cfg.Targets) == 0 {
+		cfg.Targets = []configuration.PingTarget{{Name: "google-dns", Host: "8.8.8.8"}}
+	}
+	for i, target := range cfg.Targets {
+		if target.Name == "" {
+			cfg.Targets[i].Name = target.Host
+		}
+	}
+	return &pingCollector{cfg: cfg}
+}
+
+func (*pingCollector) Name() string            { return "ping" }
+func (*pingCollector) Interval() time.Duration { return 0 }
+
+func (c *pingCollector) Collect(ctx context.Context) ([]Sample, error) {
+	var (
+		wg      sync.WaitGroup
+		results = make([][]Sample, len(c.cfg.Targets))
+	)
+	for i, target := range c.cfg.Targets {
+		wg.Add(1)
+		go func(i int, target configuration.PingTarget) {
+			defer wg.Done()
+			results[i] = c.probe(ctx, target)
+		}(i, target)
+	}
+	wg.Wait()
+
+	var samples []Sample
+	for _, result := range results {
+		samples = append(samples, result...)
+	}
+
+	// 兼容主表：取第一个目标的平均延迟与丢包率
+	for _, s := range results[0] {
+		switch s.Name {
+		case pingPrefix + "avg_rtt":
+			samples = append(samples, gauge("avg_rtt", s.Value))
+		case pingPrefix + "packet_loss":
+			samples = append(samples, gauge("packet_loss", s.Value))
+		}
+	}
+	return samples, nil
+}
+
+func (c *pingCollector) probe(ctx context.Context, target configuration.PingTarget) []Sample {
+	labels := map[string]string{"target": target.Name, "host": target.Host}
+	// 无法解析或无法发送时按全部丢包处理，保证丢包告警可以触发
+	failed := []Sample{
+		{Name: pingPrefix + "up", Value: 0, Labels: labels},
+		{Name: pingPrefix + "packet_loss", Value: 100, Labels: labels},
+	}
+
+	pinger, err := ping.NewPinger(target.Host)
+	if err != nil {
+		webLogger.Error("初始化ping报错：", zap.String("target", target.Name), zap.Error(err))
+		return failed
+	}
+	pinger.Count = c.cfg.Count
+	pinger.Interval = c.cfg.Interval
+	pinger.Timeout = c.cfg.Timeout
+	pinger.SetPrivileged(c.cfg.Privileged) // true 使用原始 socket（需要 root 权限），false 使用 UDP
+
+	pinger.OnRecv = func(pkt *ping.Packet) {
+		webLogger.Debug("ping响应", zap.String("target", target.Name), zap.String("IPAddr", pkt.IPAddr.String()), zap.Int64("Rtt", int64(pkt.Rtt)))
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			pinger.Stop()
+		case <-done:
+		}
+	}()
+
+	if err = pinger.Run(); err != nil {
+		webLogger.Error("ping运行时报错：", zap.String("target", target.Name), zap.Error(err))
+		return failed
+	}
+
+	stats := pinger.Statistics()
+	webLogger.Info("ping数据", zap.String("target", target.Name), zap.String("IPAddr", stats.Addr), zap.Float64("PacketLoss", stats.PacketLoss), zap.Duration("AvgRtt", stats.AvgRtt))
+
+	return []Sample{
+		{Name: pingPrefix + "up", Value: boolValue(stats.PacketsRecv > 0), Labels: labels},
+		{Name: pingPrefix + "min_rtt", Value: toMillis(stats.MinRtt), Labels: labels},
+		{Name: pingPrefix + "avg_rtt", Value: toMillis(stats.AvgRtt), Labels: labels},
+		{Name: pingPrefix + "max_rtt", Value: toMillis(stats.MaxRtt), Labels: labels},
+		{Name: pingPrefix + "stddev_rtt", Value: toMillis(stats.StdDevRtt), Labels: labels},
+		{Name: pingPrefix + "packet_loss", Value: util.ToDouble(stats.PacketLoss), Labels: labels},
+		{Name: pingPrefix + "packets_sent", Value: float64(stats.PacketsSent), Labels: labels},
+		{Name: pingPrefix + "packets_recv", Value: float64(stats.PacketsRecv), Labels: labels},
+	}
+}
+
+// toMillis 转为毫秒，保留4位小数
+func toMillis(d time.Duration) float64 {
+	return util.ToDouble(float64(d) / float64(time.Millisecond))
+}
@@ -0,0 +1,284 @@
+package monitor
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/solvewer/server-monitor/configuration"
+	"go.uber.org/zap"
+)
+
+// ProbeMonitor TCP/HTTP 探测结果，写入子表 server_monitor_probe，耗时单位为毫秒
+type ProbeMonitor struct {
+	Node        int       `gorm:"column:node;primaryKey"`
+	Probe       string    `gorm:"column:probe;primaryKey"`
+	Type        string    `gorm:"column:type"`
+	Target      string    `gorm:"column:target"`
+	Up          bool      `gorm:"column:up"`
+	StatusCode  int       `gorm:"column:status_code"`
+	BodyMatch   bool      `gorm:"column:body_match"`
+	DnsTime     float64   `gorm:"column:dns_time"`
+	ConnectTime float64   `gorm:"column:connect_time"`
+	TlsTime     float64   `gorm:"column:tls_time"`
+	TtfbTime    float64   `gorm:"column:ttfb_time"` // 自请求开始到收到首字节，包含 DNS、建连与 TLS
+	TotalTime   float64   `gorm:"column:total_time"`
+	CreatedAt   time.Time `gorm:"column:created_at;primaryKey"`
+}
+
+const (
+	probeTable  = "server_monitor_probe"
+	probePrefix = "probe_"
+
+	probeTCP  = "tcp"
+	probeHTTP = "http"
+
+	// probeBodyLimit 匹配响应体时最多读取的字节数
+	probeBodyLimit = 1 << 20
+)
+
+func init() {
+	RegisterTable(probeTable, ProbeMonitor{})
+}
+
+// probeResult 单次探测结果
+type probeResult struct {
+	up         bool
+	statusCode int
+	bodyMatch  bool
+	dns        time.Duration
+	connect    time.Duration
+	tls        time.Duration
+	ttfb       time.Duration
+	total      time.Duration
+}
+
+type probe struct {
+	cfg       configuration.ProbeConfig
+	bodyRegex *regexp.Regexp
+}
+
+// probeCollector 每个周期并发执行所有 TCP 端口与 HTTP(S) 探测
+type probeCollector struct {
+	probes []probe
+}
+
+func newProbeCollector(configs []configuration.ProbeConfig) (*probeCollector, error) {
+	c := &probeCollector{}
+	for _, cfg := range configs {
+		if cfg.Timeout <= 0 {
+			cfg.Timeout = 10 * time.Second
+		}
+		if cfg.Type == "" {
+			cfg.Type = probeHTTP
+		}
+
+		p := probe{cfg: cfg}
+		switch cfg.Type {
+		case probeTCP:
+			if cfg.Address == "" {
+				return nil, fmt.Errorf("探测 %s 未配置 address", cfg.Name)
+			}
+		case probeHTTP:
+			if cfg.URL == "" {
+				return nil, fmt.Errorf("探测 %s 未配置 url", cfg.Name)
+			}
+			if cfg.Method == "" {
+				p.cfg.Method = http.MethodGet
+			}
+			if len(cfg.ExpectStatus) == 0 {
+				p.cfg.ExpectStatus = []int{http.StatusOK}
+			}
+			if cfg.BodyRegex != "" {
+				var err error
+				if p.bodyRegex, err = regexp.Compile(cfg.BodyRegex); err != nil {
+					return nil, fmt.Errorf("探测 %s 的 body_regex 无效: %w", cfg.Name, err)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("探测 %s 类型无效: %s", cfg.Name, cfg.Type)
+		}
+		c.probes = append(c.probes, p)
+	}
+	return c, nil
+}
+
+func (*probeCollector) Name() string            { return "probes" }
+func (*probeCollector) Interval() time.Duration { return 0 }
+
+func (c *probeCollector) Collect(ctx context.Context) ([]Sample, error) {
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		samples []Sample
+	)
+	for _, p := range c.probes {
+		wg.Add(1)
+		go func(p probe) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(ctx, p.cfg.Timeout)
+			defer cancel()
+
+			var (
+				result probeResult
+				err    error
+				target string
+			)
+			if p.cfg.Type == probeTCP {
+				target = p.cfg.Address
+				result, err = probeTCPConnect(ctx, p.cfg.Address)
+			} else {
+				target = p.cfg.URL
+				result, err = p.probeHTTP(ctx)
+			}
+			if err != nil {
+				webLogger.Warn("探测失败", zap.String("probe", p.cfg.Name), zap.String("target", target), zap.Error(err))
+			} else {
+				webLogger.Info("探测结果", zap.String("probe", p.cfg.Name), zap.Bool("up", result.up), zap.Int("status", result.statusCode), zap.Duration("total", result.total))
+			}
+
+			labels := map[string]string{"probe": p.cfg.Name, "type": p.cfg.Type, "target": target}
+			mu.Lock()
+			samples = append(samples, result.samples(labels)...)
+			mu.Unlock()
+		}(p)
+	}
+	wg.Wait()
+
+	return samples, nil
+}
+
+func (r probeResult) samples(labels map[string]string) []Sample {
+	return []Sample{
+		{Name: probePrefix + "up", Value: boolValue(r.up), Labels: labels},
+		{Name: probePrefix + "status_code", Value: float64(r.statusCode), Labels: labels},
+		{Name: probePrefix + "body_match", Value: boolValue(r.bodyMatch), Labels: labels},
+		{Name: probePrefix + "dns_time", Value: toMillis(r.dns), Labels: labels},
+		{Name: probePrefix + "connect_time", Value: toMillis(r.connect), Labels: labels},
+		{Name: probePrefix + "tls_time", Value: toMillis(r.tls), Labels: labels},
+		{Name: probePrefix + "ttfb_time", Value: toMillis(r.ttfb), Labels: labels},
+		{Name: probePrefix + "total_time", Value: toMillis(r.total), Labels: labels},
+	}
+}
+
+// probeTCPConnect 解析域名后建立 TCP 连接，分别记录 DNS 与建连耗时
+func probeTCPConnect(ctx context.Context, address string) (probeResult, error) {
+	var result probeResult
+	start := time.Now()
+
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return result, err
+	}
+	ip := host
+	if net.ParseIP(host) == nil {
+		addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+		result.dns = time.Since(start)
+		if err != nil {
+			result.total = time.Since(start)
+			return result, err
+		}
+		ip = addrs[0]
+	}
+
+	connectStart := time.Now()
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", net.JoinHostPort(ip, port))
+	result.connect = time.Since(connectStart)
+	result.total = time.Since(start)
+	if err != nil {
+		return result, err
+	}
+	_ = conn.Close()
+
+	result.up = true
+	return result, nil
+}
+
+// probeHTTP 发起一次不复用连接的 HTTP 请求，通过 httptrace 拆分各阶段耗时
+func (p probe) probeHTTP(ctx context.Context) (probeResult, error) {
+	var (
+		result                                         probeResult
+		dnsStart, connectStart, tlsStart, requestStart time.Time
+	)
+
+	trace := &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { dnsStart = time.Now() },
+		DNSDone:           func(httptrace.DNSDoneInfo) { result.dns = time.Since(dnsStart) },
+		ConnectStart:      func(string, string) { connectStart = time.Now() },
+		ConnectDone:       func(string, string, error) { result.connect = time.Since(connectStart) },
+		TLSHandshakeStart: func() { tlsStart = time.Now() },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { result.tls = time.Since(tlsStart) },
+		GotFirstResponseByte: func() {
+			result.ttfb = time.Since(requestStart)
+		},
+	}
+
+	var body io.Reader
+	if p.cfg.Body != "" {
+		body = strings.NewReader(p.cfg.Body)
+	}
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), p.cfg.Method, p.cfg.URL, body)
+	if err != nil {
+		return result, err
+	}
+	// viper 会把 map 的键转为小写；Host 头只能通过 req.Host 生效，同时用作 SNI
+	tlsConfig := &tls.Config{InsecureSkipVerify: p.cfg.InsecureSkipVerify}
+	for k, v := range p.cfg.Headers {
+		if !strings.EqualFold(k, "Host") {
+			req.Header.Set(k, v)
+			continue
+		}
+		req.Host = v
+		tlsConfig.ServerName = v
+		if host, _, err := net.SplitHostPort(v); err == nil {
+			tlsConfig.ServerName = host
+		}
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			Proxy:             http.ProxyFromEnvironment,
+			DisableKeepAlives: true,
+			TLSClientConfig:   tlsConfig,
+		},
+	}
+	if !p.cfg.FollowRedirects {
+		client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	}
+
+	requestStart = time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		result.total = time.Since(requestStart)
+		return result, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, probeBodyLimit))
+	result.total = time.Since(requestStart)
+	result.statusCode = resp.StatusCode
+	if err != nil {
+		return result, err
+	}
+
+	result.bodyMatch = p.bodyRegex == nil || p.bodyRegex.Match(data)
+	result.up = slices.Contains(p.cfg.ExpectStatus, resp.StatusCode) && result.bodyMatch
+	return result, nil
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,61 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/solvewer/server-monitor/configuration"
+)
+
+// 经配置加载后的 Host 头（键已被 viper 转为小写）需覆盖请求的 Host
+func TestProbeHostHeader(t *testing.T) {
+	var gotHost, gotToken string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHost, gotToken = r.Host, r.Header.Get("X-Token")
+	}))
+	defer srv.Close()
+
+	_, port, _ := net.SplitHostPort(closedAddr(t))
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	yaml := fmt.Sprintf(`db:
+  host: 127.0.0.1
+  port: %s
+collectors:
+  probes:
+    - name: vhost
+      url: %s
+      headers:
+        Host: api.internal
+        X-Token: secret
+`, port, srv.URL)
+	if err := os.WriteFile(file, []byte(yaml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := configuration.Load(file); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	c, err := newProbeCollector(configuration.GetConfig().Collectors.Probes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := c.probes[0].probeHTTP(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.up {
+		t.Errorf("up = false, status %d", result.statusCode)
+	}
+	if gotHost != "api.internal" {
+		t.Errorf("Host = %q, want api.internal", gotHost)
+	}
+	if gotToken != "secret" {
+		t.Errorf("X-Token = %q, want secret", gotToken)
+	}
+}
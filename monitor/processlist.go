@@ -0,0 +1,245 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/solvewer/server-monitor/configuration"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ProcesslistMonitor 执行时间超过阈值的语句快照，写入子表 server_monitor_mysql_processlist
+type ProcesslistMonitor struct {
+	Node      int       `gorm:"column:node;primaryKey"`
+	Instance  string    `gorm:"column:instance;primaryKey"`
+	Id        uint64    `gorm:"column:id;primaryKey"` // 连接ID
+	User      string    `gorm:"column:user"`
+	Host      string    `gorm:"column:host"`
+	Db        string    `gorm:"column:db"`
+	Command   string    `gorm:"column:command"`
+	State     string    `gorm:"column:state"`
+	Time      int       `gorm:"column:time"`                 // 已执行秒数
+	SqlText   string    `gorm:"column:sql_text;type:text"`   // 截断后的原始 SQL
+	SqlDigest string    `gorm:"column:sql_digest;type:text"` // 常量替换为 ? 后的 SQL
+	Killed    bool      `gorm:"column:killed"`
+	CreatedAt time.Time `gorm:"column:created_at;primaryKey"`
+}
+
+const (
+	processlistTable  = "server_monitor_mysql_processlist"
+	processlistPrefix = "processlist_"
+)
+
+func init() {
+	RegisterTable(processlistTable, ProcesslistMonitor{})
+}
+
+var (
+	sqlStringRegexp  = regexp.MustCompile(`'(?:[^'\\]|\\.|'')*'|"(?:[^"\\]|\\.|"")*"`)
+	sqlNumberRegexp  = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)
+	sqlInListRegexp  = regexp.MustCompile(`(?i)\bIN\s*\(\s*\?(?:\s*,\s*\?)*\s*\)`)
+	sqlValuesRegexp  = regexp.MustCompile(`(?i)\bVALUES\s*\([^()]*\)(?:\s*,\s*\([^()]*\))*`)
+	whitespaceRegexp = regexp.MustCompile(`\s+`)
+)
+
+// killRule 编译后的自动终止规则
+type killRule struct {
+	configuration.KillRule
+	match *regexp.Regexp
+}
+
+func compileKillRules(rules []configuration.KillRule) ([]killRule, error) {
+	compiled := make([]killRule, 0, len(rules))
+	for _, rule := range rules {
+		r := killRule{KillRule: rule}
+		if rule.Match != "" {
+			var err error
+			if r.match, err = regexp.Compile(rule.Match); err != nil {
+				return nil, fmt.Errorf("终止规则 %s 的 match 无效: %w", rule.Name, err)
+			}
+		}
+		if rule.User == "" && rule.DB == "" && rule.Match == "" {
+			return nil, fmt.Errorf("终止规则 %s 至少需配置 user、db 或 match", rule.Name)
+		}
+		if rule.After <= 0 {
+			return nil, fmt.Errorf("终止规则 %s 需配置 after", rule.Name)
+		}
+		compiled = append(compiled, r)
+	}
+	return compiled, nil
+}
+
+// rulesFor 返回对指定实例生效的规则
+func rulesFor(rules []killRule, instance string) []killRule {
+	var list []killRule
+	for _, rule := range rules {
+		if len(rule.Instances) == 0 || slices.Contains(rule.Instances, instance) {
+			list = append(list, rule)
+		}
+	}
+	return list
+}
+
+func (r killRule) matches(p process) bool {
+	if r.User != "" && r.User != p.user {
+		return false
+	}
+	if r.DB != "" && r.DB != p.db {
+		return false
+	}
+	if r.match != nil && !r.match.MatchString(p.info) {
+		return false
+	}
+	return time.Duration(p.time)*time.Second >= r.After
+}
+
+type process struct {
+	id      uint64
+	user    string
+	host    string
+	db      string
+	command string
+	state   string
+	time    int
+	info    string
+}
+
+// processlistCollector 每个周期记录执行时间超过阈值的语句，并按规则终止失控查询；
+// 同时输出不带 process 标签的长查询数量与最长执行时间，用于告警
+type processlistCollector struct {
+	db     *gorm.DB
+	cfg    configuration.ProcesslistConfig
+	rules  []killRule
+	logger *zap.Logger
+}
+
+func (*processlistCollector) Name() string            { return "mysql_processlist" }
+func (*processlistCollector) Interval() time.Duration { return 0 }
+
+// queryThreshold 查询的执行时间下限：取快照阈值与各规则 after 中的最小值，
+// 否则 after 小于阈值的规则永远匹配不到语句
+func (c *processlistCollector) queryThreshold() time.Duration {
+	threshold := c.cfg.Threshold
+	for _, rule := range c.rules {
+		threshold = min(threshold, rule.After)
+	}
+	return threshold
+}
+
+func (c *processlistCollector) Collect(ctx context.Context) ([]Sample, error) {
+	rows, err := c.db.WithContext(ctx).Raw(
+		"SELECT ID, USER, IFNULL(HOST, ''), IFNULL(DB, ''), COMMAND, IFNULL(STATE, ''), TIME, IFNULL(INFO, '') "+
+			"FROM information_schema.PROCESSLIST "+
+			"WHERE COMMAND NOT IN ('Sleep', 'Daemon', 'Binlog Dump', 'Binlog Dump GTID', 'Connect') "+
+			"AND USER <> 'system user' AND ID <> CONNECTION_ID() AND TIME >= ? "+
+			"ORDER BY TIME DESC", int(c.queryThreshold().Seconds())).Rows()
+	if err != nil {
+		return nil, err
+	}
+
+	var processes []process
+	for rows.Next() {
+		var p process
+		if err = rows.Scan(&p.id, &p.user, &p.host, &p.db, &p.command, &p.state, &p.time, &p.info); err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		processes = append(processes, p)
+	}
+	_ = rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// 按执行时间降序，超过快照阈值的语句在前
+	long := 0
+	for long < len(processes) && time.Duration(processes[long].time)*time.Second >= c.cfg.Threshold {
+		long++
+	}
+	longest := 0
+	if len(processes) > 0 {
+		longest = processes[0].time
+	}
+	samples := []Sample{
+		gauge(processlistPrefix+"long_queries", float64(long)),
+		gauge(processlistPrefix+"longest_time", float64(longest)),
+	}
+
+	for i, p := range processes {
+		killed := c.kill(ctx, p)
+		if i >= long || i >= c.cfg.MaxRows {
+			continue
+		}
+
+		c.logger.Info("长查询", zap.Uint64("id", p.id), zap.String("user", p.user), zap.String("db", p.db), zap.Int("time", p.time), zap.String("sql", truncate(p.info, c.cfg.SQLLength)))
+		// SQL 文本与状态只写入子表，不作为序列标识，避免泄露到 /metrics 及标签基数失控
+		labels := map[string]string{"process": strconv.FormatUint(p.id, 10)}
+		attrs := map[string]string{
+			"user":       p.user,
+			"host":       p.host,
+			"db":         p.db,
+			"command":    p.command,
+			"state":      p.state,
+			"sql_text":   truncate(p.info, c.cfg.SQLLength),
+			"sql_digest": truncate(normalizeSQL(p.info), c.cfg.SQLLength),
+		}
+		samples = append(samples,
+			Sample{Name: processlistPrefix + "id", Value: float64(p.id), Labels: labels, Attrs: attrs},
+			Sample{Name: processlistPrefix + "time", Value: float64(p.time), Labels: labels},
+			Sample{Name: processlistPrefix + "killed", Value: boolValue(killed), Labels: labels},
+		)
+	}
+	return samples, nil
+}
+
+// kill 按第一条匹配的规则终止语句（KILL QUERY，不断开连接），返回是否已终止
+func (c *processlistCollector) kill(ctx context.Context, p process) bool {
+	if p.command != "Query" {
+		return false
+	}
+	for _, rule := range c.rules {
+		if !rule.matches(p) {
+			continue
+		}
+
+		fields := []zap.Field{zap.String("rule", rule.Name), zap.Uint64("id", p.id), zap.String("user", p.user), zap.Int("time", p.time), zap.String("sql", truncate(p.info, c.cfg.SQLLength))}
+		if rule.DryRun {
+			c.logger.Warn("长查询命中终止规则（dry_run，未终止）", fields...)
+			return false
+		}
+		if err := c.db.WithContext(ctx).Exec(fmt.Sprintf("KILL QUERY %d", p.id)).Error; err != nil {
+			c.logger.Error("终止长查询失败", append(fields, zap.Error(err))...)
+			return false
+		}
+		c.logger.Warn("已终止长查询", fields...)
+		return true
+	}
+	return false
+}
+
+// normalizeSQL 将字符串与数字常量替换为 ?，合并 IN 列表与多行 VALUES，压缩空白
+func normalizeSQL(sql string) string {
+	sql = sqlStringRegexp.ReplaceAllString(sql, "?")
+	sql = sqlNumberRegexp.ReplaceAllString(sql, "?")
+	sql = sqlInListRegexp.ReplaceAllString(sql, "IN (...)")
+	sql = sqlValuesRegexp.ReplaceAllString(sql, "VALUES (...)")
+	return strings.TrimSpace(whitespaceRegexp.ReplaceAllString(sql, " "))
+}
+
+// truncate 按字符截断，避免截断多字节字符
+func truncate(s string, n int) string {
+	if n <= 0 || len(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "..."
+}
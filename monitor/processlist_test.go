@@ -0,0 +1,106 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/solvewer/server-monitor/configuration"
+)
+
+func TestNormalizeSQL(t *testing.T) {
+	tests := []struct {
+		sql, want string
+	}{
+		{"SELECT * FROM orders WHERE id = 42", "SELECT * FROM orders WHERE id = ?"},
+		{"select  *\n\tfrom users where name = 'O''Brien' and email = \"a\\\"b@example.com\"",
+			"select * from users where name = ? and email = ?"},
+		{"SELECT * FROM t WHERE price > 3.14 AND id IN (1, 2, 3)", "SELECT * FROM t WHERE price > ? AND id IN (...)"},
+		{"select * from t where id in('a','b')", "select * from t where id IN (...)"},
+		{"INSERT INTO t (a, b) VALUES (1, 'x'), (2, 'y'),(3,'z')", "INSERT INTO t (a, b) VALUES (...)"},
+		// 标识符中的数字不替换
+		{"SELECT col1 FROM t2 WHERE k = 'it\\'s'", "SELECT col1 FROM t2 WHERE k = ?"},
+		{"UPDATE t SET n = n + 1 WHERE id = 7 LIMIT 10", "UPDATE t SET n = n + ? WHERE id = ? LIMIT ?"},
+		{"  ", ""},
+	}
+	for _, tt := range tests {
+		if got := normalizeSQL(tt.sql); got != tt.want {
+			t.Errorf("normalizeSQL(%q) = %q, want %q", tt.sql, got, tt.want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"select 1", 0, "select 1"},
+		{"select 1", 8, "select 1"},
+		{"select 1", 6, "select..."},
+		{"查询订单表", 5, "查询订单表"},
+		{"查询订单表", 2, "查询..."},
+	}
+	for _, tt := range tests {
+		if got := truncate(tt.s, tt.n); got != tt.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.s, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestKillRules(t *testing.T) {
+	rules, err := compileKillRules([]configuration.KillRule{
+		{Name: "report", User: "report", Match: `(?i)^\s*select`, After: 5 * time.Minute},
+		{Name: "adhoc", Instances: []string{"replica-1"}, DB: "shop", After: 2 * time.Second},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := rulesFor(rules, "primary"); len(got) != 1 || got[0].Name != "report" {
+		t.Errorf("rulesFor(primary) = %v", got)
+	}
+	replica := rulesFor(rules, "replica-1")
+	if len(replica) != 2 {
+		t.Fatalf("rulesFor(replica-1) = %d rules, want 2", len(replica))
+	}
+
+	// after 小于快照阈值时按 after 查询，否则该规则永远匹配不到
+	c := &processlistCollector{cfg: configuration.ProcesslistConfig{Threshold: 10 * time.Second}, rules: replica}
+	if got := c.queryThreshold(); got != 2*time.Second {
+		t.Errorf("queryThreshold = %v, want 2s", got)
+	}
+	c.rules = rulesFor(rules, "primary")
+	if got := c.queryThreshold(); got != 10*time.Second {
+		t.Errorf("queryThreshold = %v, want 10s", got)
+	}
+
+	tests := []struct {
+		rule int
+		p    process
+		want bool
+	}{
+		{0, process{user: "report", info: " SELECT * FROM orders", time: 301}, true},
+		{0, process{user: "report", info: "SELECT * FROM orders", time: 299}, false},
+		{0, process{user: "report", info: "UPDATE orders SET a = 1", time: 600}, false},
+		{0, process{user: "app", info: "SELECT 1", time: 600}, false},
+		{1, process{user: "app", db: "shop", info: "SELECT 1", time: 3}, true},
+		{1, process{user: "app", db: "crm", info: "SELECT 1", time: 3}, false},
+	}
+	for _, tt := range tests {
+		if got := replica[tt.rule].matches(tt.p); got != tt.want {
+			t.Errorf("%s.matches(%+v) = %v, want %v", replica[tt.rule].Name, tt.p, got, tt.want)
+		}
+	}
+
+	if _, err := compileKillRules([]configuration.KillRule{{Name: "all", After: time.Minute}}); err == nil {
+		t.Error("未配置 user/db/match 的规则应报错")
+	}
+	if _, err := compileKillRules([]configuration.KillRule{{Name: "bad", Match: "(", After: time.Minute}}); err == nil {
+		t.Error("无效的正则应报错")
+	}
+	// 未配置 after 时会在每个周期终止所有匹配的语句
+	if _, err := compileKillRules([]configuration.KillRule{{Name: "report", User: "report"}}); err == nil {
+		t.Error("未配置 after 的规则应报错")
+	}
+}
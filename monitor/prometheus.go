@@ -0,0 +1,223 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// metricPrefix 不同来源的指标名前缀，与表名保持一致
+var metricPrefix = map[string]string{
+	"web":   "server_monitor_",
+	"mysql": "server_monitor_mysql_",
+}
+
+const (
+	// seriesStaleCycles 指标连续缺失超过该倍数的上报间隔后不再输出
+	seriesStaleCycles = 3
+	// seriesStaleUnknown 只出现过一次、无法得知上报间隔的指标保留的时长，需大于最长的采集间隔
+	seriesStaleUnknown = 2 * time.Hour
+)
+
+// metricSeries 同名指标最近一次出现的样本
+type metricSeries struct {
+	samples  []Sample
+	seen     time.Time     // 最近一次出现的统计时间
+	interval time.Duration // 最近两次出现的间隔
+}
+
+// PrometheusSink 保存每个来源最近一次的快照，并以 Prometheus 文本格式对外暴露
+type PrometheusSink struct {
+	mu        sync.RWMutex
+	snapshots map[string]*Snapshot
+	// series 每个来源按指标名保存最近一次出现的样本；
+	// 采集间隔大于统计周期的指标（如证书有效期）在未采集的周期内保留上次的值，
+	// 超过 seriesStaleCycles 个上报间隔仍未出现（采集失败、目标删除）则不再输出
+	series map[string]map[string]*metricSeries
+	server *http.Server
+	logger *zap.Logger
+}
+
+// NewPrometheusSink 创建并启动内嵌的 /metrics HTTP 服务
+func NewPrometheusSink(listen, path string, logger *zap.Logger) *PrometheusSink {
+	if path == "" {
+		path = "/metrics"
+	}
+
+	s := &PrometheusSink{
+		snapshots: make(map[string]*Snapshot),
+		series:    make(map[string]map[string]*metricSeries),
+		logger:    logger,
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(path, s)
+	s.server = &http.Server{
+		Addr:              listen,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		logger.Info("metrics服务启动", zap.String("listen", listen), zap.String("path", path))
+		if err := s.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("metrics服务异常退出", zap.Error(err))
+		}
+	}()
+
+	return s
+}
+
+func (s *PrometheusSink) Name() string {
+	return "prometheus"
+}
+
+func (s *PrometheusSink) Write(_ context.Context, snapshot *Snapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snapshots[snapshot.Source] = snapshot
+
+	series, ok := s.series[snapshot.Source]
+	if !ok {
+		series = make(map[string]*metricSeries)
+		s.series[snapshot.Source] = series
+	}
+	// 本周期出现的指标整体替换，已消失的标签组合（如被移除的网卡）随之清除
+	current := make(map[string][]Sample)
+	for _, sample := range snapshot.Samples {
+		current[sample.Name] = append(current[sample.Name], sample)
+	}
+	t := snapshot.Time
+	for name, samples := range current {
+		m, ok := series[name]
+		if !ok {
+			m = &metricSeries{}
+			series[name] = m
+		}
+		if !m.seen.IsZero() && t.After(m.seen) {
+			m.interval = t.Sub(m.seen)
+		}
+		m.samples, m.seen = samples, t
+	}
+	// 本周期未出现的指标超过保留时长后清除
+	for name, m := range series {
+		after := seriesStaleUnknown
+		if m.interval > 0 {
+			after = seriesStaleCycles * m.interval
+		}
+		if t.Sub(m.seen) > after {
+			delete(series, name)
+		}
+	}
+	return nil
+}
+
+func (s *PrometheusSink) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = w.Write(s.render())
+}
+
+// render 按指标名分组输出，同名指标只输出一次 TYPE 行
+func (s *PrometheusSink) render() []byte {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	groups := make(map[string][]string)
+	for source, snapshot := range s.snapshots {
+		prefix, ok := metricPrefix[source]
+		if !ok {
+			prefix = "server_monitor_" + source + "_"
+		}
+		node := strconv.Itoa(snapshot.Node)
+
+		for _, m := range s.series[source] {
+			for _, sample := range m.samples {
+				name := prefix + sanitizeMetricName(sample.Name)
+				groups[name] = append(groups[name], name+formatLabels(node, sample.Labels)+" "+formatValue(sample.Value))
+			}
+		}
+
+		name := prefix + "last_collect_timestamp_seconds"
+		groups[name] = append(groups[name], name+formatLabels(node, nil)+" "+strconv.FormatInt(snapshot.Time.Unix(), 10))
+	}
+
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		lines := groups[name]
+		sort.Strings(lines)
+		fmt.Fprintf(&buf, "# TYPE %s gauge\n", name)
+		for _, line := range lines {
+			buf.WriteString(line)
+			buf.WriteByte('\n')
+		}
+	}
+	return buf.Bytes()
+}
+
+func formatLabels(node string, labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		if k != "node" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(`{node="`)
+	b.WriteString(node)
+	b.WriteByte('"')
+	for _, k := range keys {
+		b.WriteByte(',')
+		b.WriteString(sanitizeMetricName(k))
+		b.WriteString(`="`)
+		b.WriteString(escapeLabelValue(labels[k]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// sanitizeMetricName 将非法字符替换为下划线
+func sanitizeMetricName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r == ':' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+func escapeLabelValue(v string) string {
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, "\n", `\n`)
+	return strings.ReplaceAll(v, `"`, `\"`)
+}
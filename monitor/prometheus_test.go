@@ -0,0 +1,56 @@
+package monitor
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestPrometheusSinkExpiresVanishedSeries(t *testing.T) {
+	s := &PrometheusSink{
+		snapshots: make(map[string]*Snapshot),
+		series:    make(map[string]map[string]*metricSeries),
+		logger:    zap.NewNop(),
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	write := func(minute int, samples ...Sample) string {
+		t.Helper()
+		snapshot := &Snapshot{Source: "web", Node: 1, Time: start.Add(time.Duration(minute) * time.Minute), Samples: samples}
+		if err := s.Write(context.Background(), snapshot); err != nil {
+			t.Fatal(err)
+		}
+		return string(s.render())
+	}
+
+	cpu := gauge("cpu_usage", 10)
+	ping := Sample{Name: "ping_avg_rtt", Value: 1.5, Labels: map[string]string{"target": "gw"}}
+	cert := Sample{Name: "cert_days_left", Value: 30, Labels: map[string]string{"cert": "a"}}
+
+	write(0, cpu, ping, cert)
+	write(1, cpu, ping)
+	out := write(2, cpu, ping)
+	if !strings.Contains(out, `server_monitor_cert_days_left{node="1",cert="a"} 30`) {
+		t.Fatalf("hourly metric should be kept between collections:\n%s", out)
+	}
+
+	// ping 采集失败，连续3个周期内仍输出上次的值，之后清除
+	out = write(5, cpu)
+	if !strings.Contains(out, "server_monitor_ping_avg_rtt") {
+		t.Fatalf("ping removed too early:\n%s", out)
+	}
+	out = write(6, cpu)
+	if strings.Contains(out, "server_monitor_ping_avg_rtt") {
+		t.Fatalf("stale ping still exported:\n%s", out)
+	}
+
+	// 只出现过一次的指标保留 seriesStaleUnknown
+	if out = write(120, cpu); !strings.Contains(out, "cert_days_left") {
+		t.Fatalf("cert removed before %s:\n%s", seriesStaleUnknown, out)
+	}
+	if out = write(121, cpu); strings.Contains(out, "cert_days_left") {
+		t.Fatalf("stale cert still exported:\n%s", out)
+	}
+}
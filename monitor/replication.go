@@ -0,0 +1,228 @@
+package monitor
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ReplicaMonitor 复制状态，每个复制通道一行，写入子表 server_monitor_mysql_replica
+type ReplicaMonitor struct {
+	Node               int       `gorm:"column:node;primaryKey"`
+	Instance           string    `gorm:"column:instance;primaryKey"`
+	Channel            string    `gorm:"column:channel;primaryKey"`
+	Source             string    `gorm:"column:source"` // 主库 host:port
+	IoStatus           string    `gorm:"column:io_status"`
+	IoState            string    `gorm:"column:io_state"`
+	IoRunning          bool      `gorm:"column:io_running"`
+	SqlRunning         bool      `gorm:"column:sql_running"`
+	SecondsBehind      int64     `gorm:"column:seconds_behind"` // 复制线程未运行时为 -1
+	LastIoErrno        int       `gorm:"column:last_io_errno"`
+	LastIoError        string    `gorm:"column:last_io_error"`
+	LastSqlErrno       int       `gorm:"column:last_sql_errno"`
+	LastSqlError       string    `gorm:"column:last_sql_error"`
+	SourceLogFile      string    `gorm:"column:source_log_file"`
+	ReadSourceLogPos   uint64    `gorm:"column:read_source_log_pos"`
+	RelayLogFile       string    `gorm:"column:relay_log_file"`
+	RelayLogPos        uint64    `gorm:"column:relay_log_pos"`
+	RelaySourceLogFile string    `gorm:"column:relay_source_log_file"`
+	ExecSourceLogPos   uint64    `gorm:"column:exec_source_log_pos"`
+	RelayLogSpace      uint64    `gorm:"column:relay_log_space"`
+	GtidGap            uint64    `gorm:"column:gtid_gap"` // 已接收未执行的事务数
+	CreatedAt          time.Time `gorm:"column:created_at;primaryKey"`
+}
+
+const (
+	replicaTable  = "server_monitor_mysql_replica"
+	replicaPrefix = "replica_"
+
+	defaultChannel = "default"
+)
+
+func init() {
+	RegisterTable(replicaTable, ReplicaMonitor{})
+}
+
+// replicaCollector 解析 SHOW REPLICA STATUS，8.0.22 之前的版本回退到 SHOW SLAVE STATUS；
+// 非从库没有结果，不产生样本
+type replicaCollector struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	legacy bool // 是否使用 SHOW SLAVE STATUS
+}
+
+func (*replicaCollector) Name() string            { return "mysql_replica" }
+func (*replicaCollector) Interval() time.Duration { return 0 }
+
+func (c *replicaCollector) Collect(ctx context.Context) ([]Sample, error) {
+	rows, err := c.status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var samples []Sample
+	for _, row := range rows {
+		channel := row["Channel_Name"]
+		if channel == "" {
+			channel = defaultChannel
+		}
+		// 只以通道区分序列，线程状态、错误信息与日志文件随复制进度变化，只写入子表
+		labels := map[string]string{"channel": channel}
+		attrs := map[string]string{
+			"source":                row["Source_Host"] + ":" + row["Source_Port"],
+			"io_status":             row["Replica_IO_Running"],
+			"io_state":              row["Replica_IO_State"],
+			"last_io_error":         row["Last_IO_Error"],
+			"last_sql_error":        row["Last_SQL_Error"],
+			"source_log_file":       row["Source_Log_File"],
+			"relay_log_file":        row["Relay_Log_File"],
+			"relay_source_log_file": row["Relay_Source_Log_File"],
+		}
+
+		// 复制线程停止时 Seconds_Behind_Source 为 NULL
+		behind := float64(-1)
+		if v, err := strconv.ParseFloat(row["Seconds_Behind_Source"], 64); err == nil {
+			behind = v
+		}
+		gap := gtidGap(row["Retrieved_Gtid_Set"], row["Executed_Gtid_Set"])
+
+		c.logger.Info("复制状态",
+			zap.String("channel", channel),
+			zap.String("io", row["Replica_IO_Running"]),
+			zap.String("sql", row["Replica_SQL_Running"]),
+			zap.Float64("SecondsBehind", behind),
+			zap.Uint64("GtidGap", gap),
+		)
+
+		samples = append(samples,
+			Sample{Name: replicaPrefix + "io_running", Value: boolValue(row["Replica_IO_Running"] == "Yes"), Labels: labels, Attrs: attrs},
+			Sample{Name: replicaPrefix + "sql_running", Value: boolValue(row["Replica_SQL_Running"] == "Yes"), Labels: labels},
+			Sample{Name: replicaPrefix + "seconds_behind", Value: behind, Labels: labels},
+			Sample{Name: replicaPrefix + "last_io_errno", Value: parseFloat(row["Last_IO_Errno"]), Labels: labels},
+			Sample{Name: replicaPrefix + "last_sql_errno", Value: parseFloat(row["Last_SQL_Errno"]), Labels: labels},
+			Sample{Name: replicaPrefix + "read_source_log_pos", Value: parseFloat(row["Read_Source_Log_Pos"]), Labels: labels},
+			Sample{Name: replicaPrefix + "relay_log_pos", Value: parseFloat(row["Relay_Log_Pos"]), Labels: labels},
+			Sample{Name: replicaPrefix + "exec_source_log_pos", Value: parseFloat(row["Exec_Source_Log_Pos"]), Labels: labels},
+			Sample{Name: replicaPrefix + "relay_log_space", Value: parseFloat(row["Relay_Log_Space"]), Labels: labels},
+			Sample{Name: replicaPrefix + "gtid_gap", Value: float64(gap), Labels: labels},
+		)
+	}
+	return samples, nil
+}
+
+// status 查询复制状态，列名统一为 8.0.22 之后的 Source/Replica 命名
+func (c *replicaCollector) status(ctx context.Context) ([]map[string]string, error) {
+	query := "SHOW REPLICA STATUS"
+	if c.legacy {
+		query = "SHOW SLAVE STATUS"
+	}
+	rows, err := c.db.WithContext(ctx).Raw(query).Rows()
+	if err != nil && !c.legacy && c.needsLegacy(ctx, err) {
+		c.logger.Info("SHOW REPLICA STATUS 不可用，改用 SHOW SLAVE STATUS", zap.Error(err))
+		c.legacy = true
+		return c.status(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	replacer := strings.NewReplacer("Master", "Source", "Slave", "Replica")
+
+	var result []map[string]string
+	for rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		dest := make([]any, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err = rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]string, len(columns))
+		for i, column := range columns {
+			if values[i].Valid {
+				row[replacer.Replace(column)] = values[i].String
+			}
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+// needsLegacy 只有语法错误且服务器版本早于 MySQL 8.0.22 / MariaDB 10.5.1 时才回退，
+// 连接中断、权限不足等错误不影响之后的查询方式
+func (c *replicaCollector) needsLegacy(ctx context.Context, err error) bool {
+	if !mysqlErrorIs(err, 1064) {
+		return false
+	}
+	var version string
+	if err := c.db.WithContext(ctx).Raw("SELECT VERSION()").Scan(&version).Error; err != nil {
+		return false
+	}
+	return versionBefore(version, [3]int{8, 0, 22}, [3]int{10, 5, 1})
+}
+
+func parseFloat(s string) float64 {
+	v, _ := strconv.ParseFloat(s, 64)
+	return v
+}
+
+// gtidGap 计算已接收（Retrieved_Gtid_Set）但未执行（Executed_Gtid_Set）的事务数
+func gtidGap(retrieved, executed string) uint64 {
+	done := parseGtidSet(executed)
+
+	var gap uint64
+	for source, intervals := range parseGtidSet(retrieved) {
+		for _, in := range intervals {
+			gap += in[1] - in[0] + 1
+			for _, ex := range done[source] {
+				lo, hi := max(in[0], ex[0]), min(in[1], ex[1])
+				if lo <= hi {
+					gap -= hi - lo + 1
+				}
+			}
+		}
+	}
+	return gap
+}
+
+// parseGtidSet 解析 uuid:1-5:7,uuid2:1-3 格式的 GTID 集合，同一来源内的区间互不重叠；
+// 8.4 的标签化 GTID（uuid:tag:1-5）按 uuid:tag 区分来源
+func parseGtidSet(set string) map[string][][2]uint64 {
+	result := make(map[string][][2]uint64)
+	for _, part := range strings.Split(set, ",") {
+		fields := strings.Split(strings.ToLower(strings.TrimSpace(part)), ":")
+		if len(fields) < 2 {
+			continue
+		}
+
+		source := fields[0]
+		for _, field := range fields[1:] {
+			lo, hi, isRange := strings.Cut(field, "-")
+			start, err := strconv.ParseUint(lo, 10, 64)
+			if err != nil {
+				source = fields[0] + ":" + field
+				continue
+			}
+			end := start
+			if isRange {
+				if end, err = strconv.ParseUint(hi, 10, 64); err != nil {
+					continue
+				}
+			}
+			result[source] = append(result[source], [2]uint64{start, end})
+		}
+	}
+	return result
+}
@@ -0,0 +1,76 @@
+package monitor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseGtidSet(t *testing.T) {
+	tests := []struct {
+		set  string
+		want map[string][][2]uint64
+	}{
+		{"", map[string][][2]uint64{}},
+		{"3E11FA47-71CA-11E1-9E33-C80AA9429562:1-5", map[string][][2]uint64{
+			"3e11fa47-71ca-11e1-9e33-c80aa9429562": {{1, 5}},
+		}},
+		{"3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5:7:9-12,\n8e11fa47-71ca-11e1-9e33-c80aa9429562:1-3", map[string][][2]uint64{
+			"3e11fa47-71ca-11e1-9e33-c80aa9429562": {{1, 5}, {7, 7}, {9, 12}},
+			"8e11fa47-71ca-11e1-9e33-c80aa9429562": {{1, 3}},
+		}},
+		// 8.4 标签化 GTID：同一 uuid 下不同标签为不同来源
+		{"3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5:batch:1-2", map[string][][2]uint64{
+			"3e11fa47-71ca-11e1-9e33-c80aa9429562":       {{1, 5}},
+			"3e11fa47-71ca-11e1-9e33-c80aa9429562:batch": {{1, 2}},
+		}},
+	}
+	for _, tt := range tests {
+		if got := parseGtidSet(tt.set); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseGtidSet(%q) = %v, want %v", tt.set, got, tt.want)
+		}
+	}
+}
+
+func TestGtidGap(t *testing.T) {
+	const (
+		a = "3e11fa47-71ca-11e1-9e33-c80aa9429562"
+		b = "8e11fa47-71ca-11e1-9e33-c80aa9429562"
+	)
+	tests := []struct {
+		retrieved, executed string
+		want                uint64
+	}{
+		{"", "", 0},
+		{a + ":1-100", a + ":1-100", 0},
+		{a + ":1-100", a + ":1-90", 10},
+		// 执行集合包含本机事务与其他来源的历史事务
+		{a + ":50-100", a + ":1-95," + b + ":1-1000", 5},
+		{a + ":1-100", a + ":1-10:20-30:95-100", 100 - 10 - 11 - 6},
+		{a + ":1-10," + b + ":1-10", a + ":1-10", 10},
+		{a + ":1-5:tag:1-5", a + ":1-5", 5},
+	}
+	for _, tt := range tests {
+		if got := gtidGap(tt.retrieved, tt.executed); got != tt.want {
+			t.Errorf("gtidGap(%q, %q) = %d, want %d", tt.retrieved, tt.executed, got, tt.want)
+		}
+	}
+}
+
+func TestVersionBefore(t *testing.T) {
+	mysql, mariadb := [3]int{8, 0, 22}, [3]int{10, 5, 1}
+	tests := map[string]bool{
+		"5.7.44-log":            true,
+		"8.0.21":                true,
+		"8.0.22":                false,
+		"8.0.36-28":             false,
+		"8.4.0":                 false,
+		"10.4.32-MariaDB-log":   true,
+		"10.5.1-MariaDB":        false,
+		"10.11.6-MariaDB-0+deb": false,
+	}
+	for version, want := range tests {
+		if got := versionBefore(version, mysql, mariadb); got != want {
+			t.Errorf("versionBefore(%q) = %v, want %v", version, got, want)
+		}
+	}
+}
@@ -0,0 +1,338 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Record 待写入存储的一行数据
+type Record struct {
+	Table string // 表名
+	Value any    // 指向表结构体的指针
+}
+
+var (
+	// tables 表名与行结构体类型的对应关系，用于暂存数据的反序列化
+	tables = make(map[string]reflect.Type)
+	// mainTables 主表，写入失败时整个快照失败；其余子表写入失败只跳过该表
+	mainTables = make(map[string]bool)
+)
+
+// RegisterTable 注册表名对应的行结构体
+func RegisterTable(table string, model any) {
+	tables[table] = reflect.Indirect(reflect.ValueOf(model)).Type()
+}
+
+// registerMainTable 注册主表
+func registerMainTable(table string, model any) {
+	RegisterTable(table, model)
+	mainTables[table] = true
+}
+
+// Snapshot 一个统计周期的采集结果
+type Snapshot struct {
+	Source  string    // 来源：web / mysql
+	Node    int       // 服务器节点
+	Time    time.Time // 统计时间（整分钟）
+	Samples []Sample  // 本周期全部样本
+	Records []Record  // 按表组织的行数据
+}
+
+// Sink 采集结果的存储目标
+type Sink interface {
+	Name() string
+	Write(ctx context.Context, snapshot *Snapshot) error
+}
+
+const (
+	SinkMysql = "mysql"
+	SinkFile  = "file"
+)
+
+// sinkTimeout 单个存储目标的写入超时
+const sinkTimeout = 30 * time.Second
+
+// Fanout 将快照并行写入多个存储目标，单个目标失败不影响其他目标
+type Fanout struct {
+	mu     sync.RWMutex
+	sinks  []Sink
+	logger *zap.Logger
+}
+
+func NewFanout(logger *zap.Logger, sinks ...Sink) *Fanout {
+	return &Fanout{sinks: sinks, logger: logger}
+}
+
+func (f *Fanout) Add(s Sink) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sinks = append(f.sinks, s)
+}
+
+func (f *Fanout) Names() []string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	names := make([]string, 0, len(f.sinks))
+	for _, s := range f.sinks {
+		names = append(names, s.Name())
+	}
+	return names
+}
+
+func (f *Fanout) Name() string {
+	return "fanout"
+}
+
+func (f *Fanout) Write(ctx context.Context, snapshot *Snapshot) error {
+	f.mu.RLock()
+	sinks := append([]Sink(nil), f.sinks...)
+	f.mu.RUnlock()
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for _, s := range sinks {
+		wg.Add(1)
+		go func(s Sink) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(ctx, sinkTimeout)
+			defer cancel()
+
+			if err := s.Write(ctx, snapshot); err != nil {
+				f.logger.Error("写入存储失败", zap.String("sink", s.Name()), zap.Error(err))
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("%s: %w", s.Name(), err))
+				mu.Unlock()
+			}
+		}(s)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// MysqlSink 将快照中的行数据写入 Mysql，同一快照在一个事务内提交；
+// 自动建表推迟到写入前执行，启动时数据库不可用也不影响采集，失败的快照进入本地暂存
+type MysqlSink struct {
+	db     *gorm.DB
+	logger *zap.Logger
+
+	mu          sync.Mutex
+	autoMigrate bool
+	migrated    bool
+}
+
+func NewMysqlSink(db *gorm.DB, autoMigrate bool, logger *zap.Logger) *MysqlSink {
+	return &MysqlSink{db: db, autoMigrate: autoMigrate, logger: logger}
+}
+
+// ensureSchema 首次写入前建表，失败时下次写入重试
+func (s *MysqlSink) ensureSchema(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.autoMigrate || s.migrated {
+		return nil
+	}
+	if err := migrate(s.db.WithContext(ctx), s.logger); err != nil {
+		return err
+	}
+	s.migrated = true
+	return nil
+}
+
+func (s *MysqlSink) Name() string {
+	return SinkMysql
+}
+
+func (s *MysqlSink) Write(ctx context.Context, snapshot *Snapshot) error {
+	if err := s.ensureSchema(ctx); err != nil {
+		return err
+	}
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 子表按表设置保存点，某个子表不存在或字段缺失时回滚到保存点并跳过该表，不影响主表
+		var (
+			savepoint string
+			skipped   = make(map[string]bool)
+		)
+		for i, record := range snapshot.Records {
+			if skipped[record.Table] {
+				continue
+			}
+			child := !mainTables[record.Table]
+			if child && (i == 0 || snapshot.Records[i-1].Table != record.Table) {
+				savepoint = fmt.Sprintf("sp%d", i)
+				if err := tx.SavePoint(savepoint).Error; err != nil {
+					return err
+				}
+			}
+
+			result := tx.Table(record.Table).Create(record.Value)
+			if result.Error != nil {
+				if !child || !permanentError(result.Error) {
+					return fmt.Errorf("写入表 %s 失败: %w", record.Table, result.Error)
+				}
+				if err := tx.RollbackTo(savepoint).Error; err != nil {
+					return err
+				}
+				skipped[record.Table] = true
+				s.logger.Error("写入子表失败，已跳过", zap.String("table", record.Table), zap.Error(result.Error))
+				continue
+			}
+
+			sql := tx.ToSQL(func(tx *gorm.DB) *gorm.DB {
+				return tx.Table(record.Table).Create(record.Value)
+			})
+			s.logger.Info("sql及插入的行数",
+				zap.String("sql", sql),
+				zap.Int64("rows", result.RowsAffected),
+			)
+		}
+		return nil
+	})
+}
+
+// migrate 按注册的结构体建表，已有的表只补齐缺失的字段与索引，不修改已有字段
+func migrate(db *gorm.DB, logger *zap.Logger) error {
+	for table, model := range tables {
+		value := reflect.New(model).Interface()
+		tx := db.Table(table)
+		migrator := tx.Migrator()
+		if !migrator.HasTable(table) {
+			if err := migrator.CreateTable(value); err != nil {
+				return fmt.Errorf("自动建表 %s 失败: %w", table, err)
+			}
+			logger.Info("已创建表", zap.String("table", table))
+			continue
+		}
+
+		stmt := &gorm.Statement{DB: tx}
+		if err := stmt.Parse(value); err != nil {
+			return err
+		}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName == "" || migrator.HasColumn(value, field.DBName) {
+				continue
+			}
+			if err := migrator.AddColumn(value, field.DBName); err != nil {
+				return fmt.Errorf("表 %s 添加字段 %s 失败: %w", table, field.DBName, err)
+			}
+			logger.Info("已添加字段", zap.String("table", table), zap.String("column", field.DBName))
+		}
+		for _, index := range stmt.Schema.ParseIndexes() {
+			if migrator.HasIndex(value, index.Name) {
+				continue
+			}
+			// 已有数据可能违反新的唯一索引，只记录日志，由人工处理
+			if err := migrator.CreateIndex(value, index.Name); err != nil {
+				logger.Error("创建索引失败", zap.String("table", table), zap.String("index", index.Name), zap.Error(err))
+				continue
+			}
+			logger.Info("已创建索引", zap.String("table", table), zap.String("index", index.Name))
+		}
+	}
+	return nil
+}
+
+// FileSink 以 JSON Lines 格式将样本追加写入本地文件，便于其他系统采集
+type FileSink struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileSink(path string) (*FileSink, error) {
+	if path == "" {
+		return nil, errors.New("file sink 未配置文件路径")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	return &FileSink{path: path}, nil
+}
+
+func (s *FileSink) Name() string {
+	return SinkFile
+}
+
+func (s *FileSink) Write(_ context.Context, snapshot *Snapshot) error {
+	line, err := json.Marshal(struct {
+		Source  string    `json:"source"`
+		Node    int       `json:"node"`
+		Time    time.Time `json:"time"`
+		Samples []Sample  `json:"samples"`
+	}{snapshot.Source, snapshot.Node, snapshot.Time, snapshot.Samples})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// extraSinks 由其他模块附加的快照接收者（如告警引擎），所有命令共享
+var extraSinks []Sink
+
+// AddSink 附加快照接收者，需在 Start/StartMysql 之前调用
+func AddSink(s Sink) {
+	extraSinks = append(extraSinks, s)
+}
+
+// newSinks 根据配置创建存储目标，source 用于区分不同命令的本地暂存目录
+func newSinks(source string, names []string, logger *zap.Logger) (*Fanout, error) {
+	fanout := NewFanout(logger)
+	for _, name := range names {
+		switch name {
+		case SinkMysql:
+			var sink Sink = NewMysqlSink(db, config.Sinks.AutoMigrate, logger)
+			// 数据库不可用时写入本地暂存，恢复后回放
+			if spool := config.Sinks.Spool; spool.Enabled {
+				var err error
+				sink, err = NewSpoolSink(sink, filepath.Join(spool.Dir, source, name), int64(spool.SegmentSize)<<20, int64(spool.MaxSize)<<20, logger)
+				if err != nil {
+					return nil, err
+				}
+			}
+			fanout.Add(sink)
+		case SinkFile:
+			s, err := NewFileSink(config.Sinks.File.Path)
+			if err != nil {
+				return nil, err
+			}
+			fanout.Add(s)
+		default:
+			return nil, fmt.Errorf("未知的存储类型: %s", name)
+		}
+	}
+
+	// 配置了监听地址时启用 Prometheus /metrics 端点
+	if config.Metrics.Listen != "" {
+		fanout.Add(NewPrometheusSink(config.Metrics.Listen, config.Metrics.Path, logger))
+	}
+	for _, s := range extraSinks {
+		fanout.Add(s)
+	}
+	return fanout, nil
+}
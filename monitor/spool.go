@@ -0,0 +1,405 @@
+package monitor
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"go.uber.org/zap"
+)
+
+const (
+	segmentExt     = ".seg"
+	cursorFile     = "cursor"
+	deadLetterFile = "dead-letter.jsonl"
+
+	// replayBatch 单次写入最多回放的快照数量，避免长时间阻塞统计周期
+	replayBatch = 500
+)
+
+// spoolEntry 暂存文件中的一行
+type spoolEntry struct {
+	Source  string        `json:"source"`
+	Node    int           `json:"node"`
+	Time    time.Time     `json:"time"`
+	Samples []Sample      `json:"samples"`
+	Records []spoolRecord `json:"records"`
+}
+
+type spoolRecord struct {
+	Table string          `json:"table"`
+	Value json.RawMessage `json:"value"`
+}
+
+// SpoolSink 包装一个存储目标：写入失败时把快照追加到本地分段文件，
+// 目标恢复后按写入顺序回放，回放完成前新的快照同样先进入暂存，保证顺序
+type SpoolSink struct {
+	mu           sync.Mutex
+	inner        Sink
+	dir          string
+	segmentBytes int64
+	maxBytes     int64
+	logger       *zap.Logger
+
+	nextSeq   uint64 // 下一个新分段的序号
+	cursorSeq uint64 // 回放进度：分段序号
+	cursorOff int64  // 回放进度：分段内偏移
+}
+
+func NewSpoolSink(inner Sink, dir string, segmentBytes, maxBytes int64, logger *zap.Logger) (*SpoolSink, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	s := &SpoolSink{
+		inner:        inner,
+		dir:          dir,
+		segmentBytes: segmentBytes,
+		maxBytes:     maxBytes,
+		logger:       logger,
+	}
+
+	segments, err := s.segments()
+	if err != nil {
+		return nil, err
+	}
+	if len(segments) > 0 {
+		s.nextSeq = segments[len(segments)-1] + 1
+		logger.Warn("存在未回放的暂存数据", zap.String("sink", inner.Name()), zap.Int("segments", len(segments)))
+	}
+	s.loadCursor()
+
+	return s, nil
+}
+
+func (s *SpoolSink) Name() string {
+	return s.inner.Name()
+}
+
+func (s *SpoolSink) Write(ctx context.Context, snapshot *Snapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pending, err := s.replay(ctx)
+	if err != nil {
+		s.logger.Warn("暂存数据回放失败", zap.String("sink", s.inner.Name()), zap.Error(err))
+	}
+
+	if !pending {
+		err = s.inner.Write(ctx, snapshot)
+		if err == nil {
+			return nil
+		}
+		if permanentError(err) {
+			// 重试也不会成功，不进入暂存以免阻塞后续回放
+			line, encodeErr := encodeEntry(snapshot)
+			if encodeErr == nil {
+				s.deadLetter(line, err)
+			}
+			return err
+		}
+		s.logger.Warn("写入失败，快照转入本地暂存", zap.String("sink", s.inner.Name()), zap.Error(err))
+	}
+
+	if err := s.append(snapshot); err != nil {
+		return fmt.Errorf("写入本地暂存失败: %w", err)
+	}
+	return nil
+}
+
+// permanentError 是否为重试也无法成功的写入错误：表或字段不存在、主键冲突、数据不合法等
+func permanentError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return false
+	}
+	switch mysqlErr.Number {
+	case 1048, // 字段不能为 NULL
+		1054, // 字段不存在
+		1062, // 主键冲突（已提交但未收到确认后重放）
+		1136, // 字段数不匹配
+		1146, // 表不存在
+		1264, // 数值越界
+		1292, // 值格式错误
+		1364, // 字段没有默认值
+		1366, // 值不合法
+		1406: // 数据过长
+		return true
+	}
+	return false
+}
+
+// deadLetter 无法写入的快照移入死信文件，超过分段大小时轮转一次，便于人工排查后补录
+func (s *SpoolSink) deadLetter(line []byte, cause error) {
+	s.logger.Error("快照无法写入，已移入死信文件", zap.String("sink", s.inner.Name()), zap.Error(cause))
+
+	path := filepath.Join(s.dir, deadLetterFile)
+	if info, err := os.Stat(path); err == nil && info.Size()+int64(len(line)) > s.segmentBytes {
+		_ = os.Rename(path, path+".1")
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		s.logger.Error("写入死信文件失败", zap.Error(err))
+		return
+	}
+	defer f.Close()
+	if _, err = f.Write(line); err != nil {
+		s.logger.Error("写入死信文件失败", zap.Error(err))
+	}
+}
+
+// replay 按顺序回放暂存数据，返回是否仍有未回放的数据
+func (s *SpoolSink) replay(ctx context.Context) (bool, error) {
+	segments, err := s.segments()
+	if err != nil || len(segments) == 0 {
+		return len(segments) > 0, err
+	}
+
+	replayed := 0
+	defer func() {
+		if replayed > 0 {
+			s.logger.Info("暂存数据回放", zap.String("sink", s.inner.Name()), zap.Int("snapshots", replayed))
+		}
+	}()
+
+	for _, seq := range segments {
+		if seq < s.cursorSeq {
+			// 游标之前的分段已回放完，清理残留
+			_ = os.Remove(s.segmentPath(seq))
+			continue
+		}
+		if seq > s.cursorSeq {
+			s.cursorSeq, s.cursorOff = seq, 0
+		}
+
+		done, n, err := s.replaySegment(ctx, seq, replayBatch-replayed)
+		replayed += n
+		if err != nil || !done {
+			return true, err
+		}
+
+		if err = os.Remove(s.segmentPath(seq)); err != nil {
+			return true, err
+		}
+		s.cursorSeq, s.cursorOff = seq+1, 0
+		s.saveCursor()
+	}
+	return false, nil
+}
+
+// replaySegment 从游标位置回放一个分段，返回该分段是否已回放完
+func (s *SpoolSink) replaySegment(ctx context.Context, seq uint64, limit int) (bool, int, error) {
+	f, err := os.Open(s.segmentPath(seq))
+	if err != nil {
+		return false, 0, err
+	}
+	defer f.Close()
+
+	if _, err = f.Seek(s.cursorOff, io.SeekStart); err != nil {
+		return false, 0, err
+	}
+
+	n := 0
+	reader := bufio.NewReader(f)
+	for {
+		if n >= limit || ctx.Err() != nil {
+			return false, n, ctx.Err()
+		}
+
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// 末尾不完整的行（写入时进程退出）直接丢弃
+			return true, n, nil
+		}
+		if err != nil {
+			return false, n, err
+		}
+
+		snapshot, err := decodeEntry(line)
+		if err != nil {
+			s.logger.Error("暂存数据解析失败，已跳过", zap.Uint64("segment", seq), zap.Int64("offset", s.cursorOff), zap.Error(err))
+		} else if err = s.inner.Write(ctx, snapshot); err != nil {
+			if !permanentError(err) {
+				return false, n, err
+			}
+			s.deadLetter(line, err)
+		}
+
+		n++
+		s.cursorOff += int64(len(line))
+		s.saveCursor()
+	}
+}
+
+// append 追加快照到最新分段，超过分段大小时新建分段，超过总容量时丢弃最旧的分段
+func (s *SpoolSink) append(snapshot *Snapshot) error {
+	line, err := encodeEntry(snapshot)
+	if err != nil {
+		return err
+	}
+
+	segments, err := s.segments()
+	if err != nil {
+		return err
+	}
+	seq := s.nextSeq
+	if len(segments) > 0 {
+		last := segments[len(segments)-1]
+		if info, err := os.Stat(s.segmentPath(last)); err == nil && info.Size()+int64(len(line)) <= s.segmentBytes {
+			seq = last
+		}
+	}
+	if seq >= s.nextSeq {
+		s.nextSeq = seq + 1
+	}
+
+	f, err := os.OpenFile(s.segmentPath(seq), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(line); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+
+	s.enforceCap()
+	return nil
+}
+
+// enforceCap 总大小超过上限时删除最旧的分段
+func (s *SpoolSink) enforceCap() {
+	segments, err := s.segments()
+	if err != nil {
+		return
+	}
+
+	sizes := make([]int64, len(segments))
+	var total int64
+	for i, seq := range segments {
+		if info, err := os.Stat(s.segmentPath(seq)); err == nil {
+			sizes[i] = info.Size()
+			total += sizes[i]
+		}
+	}
+
+	// 至少保留最新的分段
+	for i := 0; total > s.maxBytes && i < len(segments)-1; i++ {
+		if err := os.Remove(s.segmentPath(segments[i])); err != nil {
+			continue
+		}
+		total -= sizes[i]
+		s.logger.Warn("暂存容量超限，丢弃最旧的分段", zap.String("sink", s.inner.Name()), zap.Uint64("segment", segments[i]), zap.Int64("bytes", sizes[i]))
+		if segments[i] >= s.cursorSeq {
+			s.cursorSeq, s.cursorOff = segments[i]+1, 0
+			s.saveCursor()
+		}
+	}
+}
+
+// segments 返回按序号升序排列的分段
+func (s *SpoolSink) segments() ([]uint64, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var segments []uint64
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), segmentExt)
+		if !ok {
+			continue
+		}
+		if seq, err := strconv.ParseUint(name, 10, 64); err == nil {
+			segments = append(segments, seq)
+		}
+	}
+	sort.Slice(segments, func(i, j int) bool { return segments[i] < segments[j] })
+	return segments, nil
+}
+
+func (s *SpoolSink) segmentPath(seq uint64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%020d%s", seq, segmentExt))
+}
+
+func (s *SpoolSink) loadCursor() {
+	data, err := os.ReadFile(filepath.Join(s.dir, cursorFile))
+	if err != nil {
+		return
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) != 2 {
+		return
+	}
+	s.cursorSeq, _ = strconv.ParseUint(fields[0], 10, 64)
+	s.cursorOff, _ = strconv.ParseInt(fields[1], 10, 64)
+}
+
+func (s *SpoolSink) saveCursor() {
+	data := fmt.Sprintf("%d %d\n", s.cursorSeq, s.cursorOff)
+	if err := os.WriteFile(filepath.Join(s.dir, cursorFile), []byte(data), 0644); err != nil {
+		s.logger.Error("保存暂存回放进度失败", zap.Error(err))
+	}
+}
+
+func encodeEntry(snapshot *Snapshot) ([]byte, error) {
+	entry := spoolEntry{
+		Source:  snapshot.Source,
+		Node:    snapshot.Node,
+		Time:    snapshot.Time,
+		Samples: snapshot.Samples,
+	}
+	for _, record := range snapshot.Records {
+		value, err := json.Marshal(record.Value)
+		if err != nil {
+			return nil, err
+		}
+		entry.Records = append(entry.Records, spoolRecord{Table: record.Table, Value: value})
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	return append(line, '\n'), nil
+}
+
+func decodeEntry(line []byte) (*Snapshot, error) {
+	var entry spoolEntry
+	if err := json.Unmarshal(line, &entry); err != nil {
+		return nil, err
+	}
+
+	snapshot := &Snapshot{
+		Source:  entry.Source,
+		Node:    entry.Node,
+		Time:    entry.Time,
+		Samples: entry.Samples,
+	}
+	for _, record := range entry.Records {
+		model, ok := tables[record.Table]
+		if !ok {
+			return nil, fmt.Errorf("未注册的表: %s", record.Table)
+		}
+		value := reflect.New(model).Interface()
+		if err := json.Unmarshal(record.Value, value); err != nil {
+			return nil, err
+		}
+		snapshot.Records = append(snapshot.Records, Record{Table: record.Table, Value: value})
+	}
+	return snapshot, nil
+}
@@ -0,0 +1,181 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/solvewer/server-monitor/configuration"
+	"go.uber.org/zap"
+)
+
+// fakeSink 按预设的错误依次返回，并记录写入成功的快照
+type fakeSink struct {
+	errs    []error
+	written []int
+}
+
+func (*fakeSink) Name() string { return "fake" }
+
+func (f *fakeSink) Write(_ context.Context, snapshot *Snapshot) error {
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		if err != nil {
+			return err
+		}
+	}
+	f.written = append(f.written, snapshot.Node)
+	return nil
+}
+
+func newTestSpool(t *testing.T, inner Sink) *SpoolSink {
+	t.Helper()
+	s, err := NewSpoolSink(inner, t.TempDir(), 1<<20, 8<<20, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func snapshotOf(node int) *Snapshot {
+	return &Snapshot{Source: "test", Node: node, Time: time.Unix(int64(node), 0)}
+}
+
+func TestSpoolReplayInOrder(t *testing.T) {
+	down := errors.New("connection refused")
+	inner := &fakeSink{errs: []error{down, down}}
+	s := newTestSpool(t, inner)
+
+	ctx := context.Background()
+	for node := 1; node <= 3; node++ {
+		if err := s.Write(ctx, snapshotOf(node)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 第1个写入失败进入暂存，第2个回放时失败、同样进入暂存，第3个时恢复并按顺序回放
+	if fmt.Sprint(inner.written) != "[1 2 3]" {
+		t.Fatalf("written = %v, want [1 2 3]", inner.written)
+	}
+	if segments, _ := s.segments(); len(segments) != 0 {
+		t.Fatalf("segments = %v, want none", segments)
+	}
+}
+
+func TestSpoolPermanentErrorDoesNotBlock(t *testing.T) {
+	inner := &fakeSink{errs: []error{
+		errors.New("connection refused"), // 1 进入暂存
+		&mysql.MySQLError{Number: 1146},  // 回放 1 时表不存在，移入死信
+		nil,                              // 2 正常写入
+		&mysql.MySQLError{Number: 1062},  // 3 主键冲突，直接移入死信
+	}}
+	s := newTestSpool(t, inner)
+
+	ctx := context.Background()
+	_ = s.Write(ctx, snapshotOf(1))
+	if err := s.Write(ctx, snapshotOf(2)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Write(ctx, snapshotOf(3)); err == nil {
+		t.Fatal("expected permanent error to be returned")
+	}
+	if err := s.Write(ctx, snapshotOf(4)); err != nil {
+		t.Fatal(err)
+	}
+
+	if fmt.Sprint(inner.written) != "[2 4]" {
+		t.Fatalf("written = %v, want [2 4]", inner.written)
+	}
+	if segments, _ := s.segments(); len(segments) != 0 {
+		t.Fatalf("segments = %v, want none", segments)
+	}
+	data, err := os.ReadFile(filepath.Join(s.dir, deadLetterFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var nodes []int
+	for _, line := range bytes.Split(bytes.TrimSpace(data), []byte("\n")) {
+		snapshot, err := decodeEntry(line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nodes = append(nodes, snapshot.Node)
+	}
+	if fmt.Sprint(nodes) != "[1 3]" {
+		t.Fatalf("dead letters = %v, want [1 3]", nodes)
+	}
+}
+
+func TestPermanentError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{errors.New("i/o timeout"), false},
+		{context.DeadlineExceeded, false},
+		{&mysql.MySQLError{Number: 1045}, false},
+		{&mysql.MySQLError{Number: 1146}, true},
+		{fmt.Errorf("写入表 x 失败: %w", &mysql.MySQLError{Number: 1054}), true},
+	}
+	for _, tt := range tests {
+		if got := permanentError(tt.err); got != tt.want {
+			t.Errorf("permanentError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+// closedAddr 返回一个没有监听的本地地址
+func closedAddr(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("无法监听 TCP: %v", err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	return addr
+}
+
+// 启动时存储库不可用：不建表、不报错，快照进入暂存
+func TestSpoolDatabaseDownAtStart(t *testing.T) {
+	conn, err := configuration.OpenMysql("monitor:secret@tcp(" + closedAddr(t) + ")/monitor?timeout=1s&parseTime=True")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink := NewMysqlSink(conn, true, zap.NewNop())
+	s := newTestSpool(t, sink)
+
+	ctx := context.Background()
+	for node := 1; node <= 2; node++ {
+		snapshot := snapshotOf(node)
+		snapshot.Records = []Record{{Table: "server_monitor", Value: &ServerMonitor{Node: node, CreatedAt: snapshot.Time}}}
+		if err := s.Write(ctx, snapshot); err != nil {
+			t.Fatalf("写入应转入暂存: %v", err)
+		}
+	}
+	if sink.migrated {
+		t.Error("数据库不可用时不应标记为已建表")
+	}
+
+	segments, err := s.segments()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segments) != 1 {
+		t.Fatalf("segments = %v, want 1", segments)
+	}
+	data, err := os.ReadFile(s.segmentPath(segments[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := bytes.Count(data, []byte("\n")); lines != 2 {
+		t.Errorf("暂存 %d 个快照, want 2", lines)
+	}
+}
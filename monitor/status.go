@@ -0,0 +1,252 @@
+package monitor
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/solvewer/server-monitor/configuration"
+	"github.com/solvewer/server-monitor/util"
+	"gorm.io/gorm"
+)
+
+// StatusMonitor 按配置记录的状态/系统变量，每个指标一行，写入子表 server_monitor_mysql_status
+type StatusMonitor struct {
+	Node      int       `gorm:"column:node;primaryKey"`
+	Instance  string    `gorm:"column:instance;primaryKey"`
+	Metric    string    `gorm:"column:metric;primaryKey"` // 如 aborted_connects_rate、max_connections
+	Kind      string    `gorm:"column:kind"`              // rate / gauge / variable / derived
+	Value     float64   `gorm:"column:value"`
+	CreatedAt time.Time `gorm:"column:created_at;primaryKey"`
+}
+
+const (
+	statusTable  = "server_monitor_mysql_status"
+	statusPrefix = "status_"
+
+	// statusCacheTTL 同一周期内各采集器共享查询结果的有效期
+	statusCacheTTL = 10 * time.Second
+)
+
+func init() {
+	RegisterTable(statusTable, StatusMonitor{})
+}
+
+// statusCache 同一实例的采集器共享的 SHOW GLOBAL STATUS 与 SHOW GLOBAL VARIABLES 结果，
+// 通过 performance_schema 一次查询取回，每个周期只查询一次
+type statusCache struct {
+	db *gorm.DB
+
+	mu        sync.Mutex
+	at        time.Time
+	status    map[string]float64
+	variables map[string]float64
+	legacy    bool // performance_schema 不可用时分别执行 SHOW 语句
+}
+
+func newStatusCache(db *gorm.DB) *statusCache {
+	return &statusCache{db: db}
+}
+
+// get 返回状态变量、系统变量及查询时间，调用方不得修改返回的 map
+func (c *statusCache) get(ctx context.Context) (map[string]float64, map[string]float64, time.Time, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Since(c.at) < statusCacheTTL {
+		return c.status, c.variables, c.at, nil
+	}
+
+	status, variables, err := c.fetch(ctx)
+	if err != nil {
+		return nil, nil, time.Time{}, err
+	}
+	c.status, c.variables, c.at = status, variables, time.Now()
+	return c.status, c.variables, c.at, nil
+}
+
+func (c *statusCache) fetch(ctx context.Context) (map[string]float64, map[string]float64, error) {
+	status := make(map[string]float64)
+	variables := make(map[string]float64)
+
+	if !c.legacy {
+		rows, err := c.db.WithContext(ctx).Raw(
+			"SELECT 'status', VARIABLE_NAME, VARIABLE_VALUE FROM performance_schema.global_status " +
+				"UNION ALL SELECT 'variable', VARIABLE_NAME, VARIABLE_VALUE FROM performance_schema.global_variables").Rows()
+		// 连接中断、超时等错误不改变查询方式，下个周期重试
+		if err != nil && !mysqlErrorIs(err, 1044, 1142, 1146, 1227) {
+			return nil, nil, err
+		}
+		if err == nil {
+			for rows.Next() {
+				var kind, name, value string
+				if err = rows.Scan(&kind, &name, &value); err != nil {
+					_ = rows.Close()
+					return nil, nil, err
+				}
+				if kind == "status" {
+					putNumeric(status, name, value)
+				} else {
+					putNumeric(variables, name, value)
+				}
+			}
+			_ = rows.Close()
+			if err = rows.Err(); err != nil {
+				return nil, nil, err
+			}
+			if len(status) > 0 {
+				return status, variables, nil
+			}
+		}
+		// 5.6 没有这两张表、没有 performance_schema 的查询权限，或关闭 performance_schema 时表为空，
+		// 回退到 SHOW 语句
+		c.legacy = true
+	}
+
+	for query, target := range map[string]map[string]float64{"SHOW GLOBAL STATUS": status, "SHOW GLOBAL VARIABLES": variables} {
+		rows, err := c.db.WithContext(ctx).Raw(query).Rows()
+		if err != nil {
+			return nil, nil, err
+		}
+		for rows.Next() {
+			var name, value string
+			if err = rows.Scan(&name, &value); err != nil {
+				_ = rows.Close()
+				return nil, nil, err
+			}
+			putNumeric(target, name, value)
+		}
+		_ = rows.Close()
+		if err = rows.Err(); err != nil {
+			return nil, nil, err
+		}
+	}
+	return status, variables, nil
+}
+
+// putNumeric 只保留数值，ON/OFF 记为 1/0；变量名统一为 SHOW 语句的大小写
+func putNumeric(m map[string]float64, name, value string) {
+	switch strings.ToUpper(value) {
+	case "ON", "YES":
+		m[canonicalName(name)] = 1
+		return
+	case "OFF", "NO":
+		m[canonicalName(name)] = 0
+		return
+	}
+	if v, err := strconv.ParseFloat(value, 64); err == nil {
+		m[canonicalName(name)] = v
+	}
+}
+
+// canonicalName performance_schema 中的名称为大写，转为 SHOW 语句中的首字母大写形式；
+// 系统变量在 SHOW 中为小写，统一在查找时按小写处理
+func canonicalName(name string) string {
+	if name == "" {
+		return name
+	}
+	lower := strings.ToLower(name)
+	return strings.ToUpper(lower[:1]) + lower[1:]
+}
+
+// lookup 按名称取值，忽略大小写差异
+func lookup(m map[string]float64, name string) (float64, bool) {
+	v, ok := m[canonicalName(name)]
+	return v, ok
+}
+
+// statusCollector 按配置从共享的状态结果中记录速率、当前值与系统变量，
+// 并计算连接数占 max_connections 的比例
+type statusCollector struct {
+	cache     *statusCache
+	rates     nameFilter
+	gauges    nameFilter
+	variables nameFilter
+	counters  counterScope
+}
+
+func newStatusCollector(cache *statusCache, counters counterScope, cfg configuration.StatusConfig) *statusCollector {
+	canonical := func(patterns []string) []string {
+		list := make([]string, 0, len(patterns))
+		for _, p := range patterns {
+			list = append(list, canonicalName(p))
+		}
+		return list
+	}
+	return &statusCollector{
+		cache:     cache,
+		counters:  counters,
+		rates:     newNameFilter(canonical(cfg.Rates), nil),
+		gauges:    newNameFilter(canonical(cfg.Gauges), nil),
+		variables: newNameFilter(canonical(cfg.Variables), nil),
+	}
+}
+
+func (*statusCollector) Name() string            { return "mysql_status" }
+func (*statusCollector) Interval() time.Duration { return 0 }
+
+func (c *statusCollector) Collect(ctx context.Context) ([]Sample, error) {
+	status, variables, at, err := c.cache.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var samples []Sample
+	metric := func(kind, name string, value float64) {
+		labels := map[string]string{"metric": name, "kind": kind}
+		samples = append(samples, Sample{Name: statusPrefix + name, Value: value, Labels: labels})
+	}
+
+	for name, v := range status {
+		// 首次观测与计数回退（实例重启）时跳过该指标
+		if configured(c.rates, name) {
+			if rate, ok := c.counters.rate("status:"+name, v, at); ok {
+				metric("rate", strings.ToLower(name)+"_rate", util.ToDouble(rate))
+			}
+		}
+		if configured(c.gauges, name) {
+			metric("gauge", strings.ToLower(name), v)
+		}
+	}
+	for name, v := range variables {
+		if configured(c.variables, name) {
+			metric("variable", strings.ToLower(name), v)
+		}
+	}
+
+	if maxConnections, ok := lookup(variables, "max_connections"); ok && maxConnections > 0 {
+		if used, ok := lookup(status, "Max_used_connections"); ok {
+			metric("derived", "max_used_connections_usage", util.ToDouble(used*100/maxConnections))
+		}
+		if connected, ok := lookup(status, "Threads_connected"); ok {
+			metric("derived", "connections_usage", util.ToDouble(connected*100/maxConnections))
+		}
+	}
+	return samples, nil
+}
+
+// configured 名称是否命中配置的模式，未配置时不记录
+func configured(f nameFilter, name string) bool {
+	return len(f.include) > 0 && f.match(name)
+}
+
+// statusRecords 每个指标生成一行子表数据
+func statusRecords(samples []Sample, node int, t time.Time) []Record {
+	var records []Record
+	for _, s := range samples {
+		if !strings.HasPrefix(s.Name, statusPrefix) || s.Labels["metric"] == "" {
+			continue
+		}
+		records = append(records, Record{Table: statusTable, Value: &StatusMonitor{
+			Node:      node,
+			Instance:  s.Labels["instance"],
+			Metric:    s.Labels["metric"],
+			Kind:      s.Labels["kind"],
+			Value:     s.Value,
+			CreatedAt: t,
+		}})
+	}
+	return records
+}
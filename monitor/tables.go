@@ -0,0 +1,320 @@
+package monitor
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/disk"
+	"github.com/solvewer/server-monitor/configuration"
+	"github.com/solvewer/server-monitor/util"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// TableSizeMonitor 单表空间，写入子表 server_monitor_mysql_table；增长率单位为字节/天
+type TableSizeMonitor struct {
+	Node        int       `gorm:"column:node;primaryKey"`
+	Instance    string    `gorm:"column:instance;primaryKey"`
+	SchemaName  string    `gorm:"column:schema_name;primaryKey"`
+	TableName   string    `gorm:"column:table_name;primaryKey"`
+	Engine      string    `gorm:"column:engine"`
+	DataLength  uint64    `gorm:"column:data_length"`
+	IndexLength uint64    `gorm:"column:index_length"`
+	DataFree    uint64    `gorm:"column:data_free"`
+	TableRows   uint64    `gorm:"column:table_rows"` // 估算值
+	TotalSize   uint64    `gorm:"column:total_size"` // data_length + index_length
+	GrowthRate  float64   `gorm:"column:growth_rate"`
+	CreatedAt   time.Time `gorm:"column:created_at;primaryKey"`
+}
+
+// SchemaSizeMonitor 库汇总，写入子表 server_monitor_mysql_schema
+type SchemaSizeMonitor struct {
+	Node        int       `gorm:"column:node;primaryKey"`
+	Instance    string    `gorm:"column:instance;primaryKey"`
+	SchemaName  string    `gorm:"column:schema_name;primaryKey"`
+	Tables      int       `gorm:"column:tables"`
+	DataLength  uint64    `gorm:"column:data_length"`
+	IndexLength uint64    `gorm:"column:index_length"`
+	DataFree    uint64    `gorm:"column:data_free"` // 只含独立表空间与非 InnoDB 表，共享表空间的空闲空间不属于单个库
+	TableRows   uint64    `gorm:"column:table_rows"`
+	TotalSize   uint64    `gorm:"column:total_size"`
+	GrowthRate  float64   `gorm:"column:growth_rate"`
+	CreatedAt   time.Time `gorm:"column:created_at;primaryKey"`
+}
+
+// DatadirMonitor 数据目录所在文件系统的容量预测，写入子表 server_monitor_mysql_datadir
+type DatadirMonitor struct {
+	Node          int       `gorm:"column:node;primaryKey"`
+	Instance      string    `gorm:"column:instance;primaryKey"`
+	Datadir       string    `gorm:"column:datadir"`
+	DataSize      uint64    `gorm:"column:data_size"` // 所有表合计
+	GrowthRate    float64   `gorm:"column:growth_rate"`
+	FsTotal       uint64    `gorm:"column:fs_total"` // 仅本机实例
+	FsFree        uint64    `gorm:"column:fs_free"`
+	DaysUntilFull float64   `gorm:"column:days_until_full"` // 无增长趋势时为0
+	CreatedAt     time.Time `gorm:"column:created_at;primaryKey"`
+}
+
+const (
+	tableSizeTable   = "server_monitor_mysql_table"
+	tableSizePrefix  = "table_"
+	schemaSizeTable  = "server_monitor_mysql_schema"
+	schemaSizePrefix = "schema_"
+	datadirTable     = "server_monitor_mysql_datadir"
+	datadirPrefix    = "datadir_"
+
+	// growthWindow 容量预测使用的增长趋势窗口
+	growthWindow = 24 * time.Hour
+)
+
+func init() {
+	RegisterTable(tableSizeTable, TableSizeMonitor{})
+	RegisterTable(schemaSizeTable, SchemaSizeMonitor{})
+	RegisterTable(datadirTable, DatadirMonitor{})
+}
+
+type tableSize struct {
+	schema, name, engine                    string
+	dataLength, indexLength, dataFree, rows uint64
+}
+
+func (t tableSize) total() uint64 {
+	return t.dataLength + t.indexLength
+}
+
+type sizePoint struct {
+	at   time.Time
+	size uint64
+}
+
+// tablesCollector 每小时读取 information_schema.TABLES，记录表与库的空间及其增长率；
+// 本机实例按最近24小时的增长趋势预测数据目录所在文件系统写满的天数
+type tablesCollector struct {
+	db     *gorm.DB
+	cfg    configuration.TablesConfig
+	local  bool
+	logger *zap.Logger
+
+	prev    map[string]uint64 // 上次采集的表/库大小
+	prevAt  time.Time
+	history []sizePoint // 合计大小，保留 growthWindow 内的采样点
+}
+
+func (*tablesCollector) Name() string            { return "mysql_tables" }
+func (*tablesCollector) Interval() time.Duration { return time.Hour }
+
+func (c *tablesCollector) Collect(ctx context.Context) ([]Sample, error) {
+	var (
+		tables  []tableSize
+		spaces  map[string][]uint64
+		datadir string
+	)
+	// 8.0 默认缓存表统计信息一天，在同一连接上关闭缓存后再查询
+	err := c.db.WithContext(ctx).Connection(func(tx *gorm.DB) error {
+		_ = tx.Exec("SET SESSION information_schema_stats_expiry = 0").Error
+		if err := tx.Raw("SELECT @@datadir").Row().Scan(&datadir); err != nil {
+			return err
+		}
+
+		rows, err := tx.Raw("SELECT TABLE_SCHEMA, TABLE_NAME, IFNULL(ENGINE, ''), IFNULL(DATA_LENGTH, 0), IFNULL(INDEX_LENGTH, 0), IFNULL(DATA_FREE, 0), IFNULL(TABLE_ROWS, 0) " +
+			"FROM information_schema.TABLES WHERE TABLE_TYPE = 'BASE TABLE' " +
+			"AND TABLE_SCHEMA NOT IN ('mysql', 'information_schema', 'performance_schema', 'sys')").Rows()
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var t tableSize
+			if err = rows.Scan(&t.schema, &t.name, &t.engine, &t.dataLength, &t.indexLength, &t.dataFree, &t.rows); err != nil {
+				return err
+			}
+			tables = append(tables, t)
+		}
+		if err = rows.Err(); err != nil {
+			return err
+		}
+
+		if spaces, err = tablespaces(tx); err != nil {
+			c.logger.Warn("读取 InnoDB 表空间失败，库汇总不计入 InnoDB 表的空闲空间", zap.Error(err))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	ownFree := exclusiveFree(tables, spaces)
+
+	var (
+		order   []string
+		total   uint64
+		schemas = make(map[string]*tableSize)
+		counts  = make(map[string]int)
+	)
+	for i, t := range tables {
+		s, ok := schemas[t.schema]
+		if !ok {
+			s = &tableSize{schema: t.schema}
+			schemas[t.schema] = s
+			order = append(order, t.schema)
+		}
+		s.dataLength += t.dataLength
+		s.indexLength += t.indexLength
+		if ownFree[i] {
+			s.dataFree += t.dataFree
+		}
+		s.rows += t.rows
+		counts[t.schema]++
+		total += t.total()
+	}
+
+	current := make(map[string]uint64, len(tables)+len(schemas))
+	var samples []Sample
+
+	minSize := uint64(c.cfg.MinSize) << 20
+	for _, t := range tables {
+		key := t.schema + "." + t.name
+		current[key] = t.total()
+		if t.total() < minSize {
+			continue
+		}
+		labels := map[string]string{"table": key, "schema_name": t.schema, "table_name": t.name, "engine": t.engine}
+		samples = append(samples,
+			Sample{Name: tableSizePrefix + "data_length", Value: float64(t.dataLength), Labels: labels},
+			Sample{Name: tableSizePrefix + "index_length", Value: float64(t.indexLength), Labels: labels},
+			Sample{Name: tableSizePrefix + "data_free", Value: float64(t.dataFree), Labels: labels},
+			Sample{Name: tableSizePrefix + "table_rows", Value: float64(t.rows), Labels: labels},
+			Sample{Name: tableSizePrefix + "total_size", Value: float64(t.total()), Labels: labels},
+		)
+		if rate, ok := c.growth(key, t.total(), now); ok {
+			samples = append(samples, Sample{Name: tableSizePrefix + "growth_rate", Value: rate, Labels: labels})
+		}
+	}
+
+	for _, name := range order {
+		s := schemas[name]
+		key := name + ".*"
+		current[key] = s.total()
+		labels := map[string]string{"schema_name": name}
+		samples = append(samples,
+			Sample{Name: schemaSizePrefix + "tables", Value: float64(counts[name]), Labels: labels},
+			Sample{Name: schemaSizePrefix + "data_length", Value: float64(s.dataLength), Labels: labels},
+			Sample{Name: schemaSizePrefix + "index_length", Value: float64(s.indexLength), Labels: labels},
+			Sample{Name: schemaSizePrefix + "data_free", Value: float64(s.dataFree), Labels: labels},
+			Sample{Name: schemaSizePrefix + "table_rows", Value: float64(s.rows), Labels: labels},
+			Sample{Name: schemaSizePrefix + "total_size", Value: float64(s.total()), Labels: labels},
+		)
+		if rate, ok := c.growth(key, s.total(), now); ok {
+			samples = append(samples, Sample{Name: schemaSizePrefix + "growth_rate", Value: rate, Labels: labels})
+		}
+	}
+
+	samples = append(samples, c.capacity(ctx, datadir, total, now)...)
+	c.prev, c.prevAt = current, now
+
+	return samples, nil
+}
+
+// tablespaces 返回 InnoDB 表（含各分区）所在的表空间，键为 schema/table；
+// 8.0 读取 INNODB_TABLES，5.7 与 MariaDB 读取 INNODB_SYS_TABLES
+func tablespaces(tx *gorm.DB) (map[string][]uint64, error) {
+	rows, err := tx.Raw("SELECT NAME, SPACE FROM information_schema.INNODB_TABLES").Rows()
+	if mysqlErrorIs(err, 1109) { // 未知的 information_schema 表
+		rows, err = tx.Raw("SELECT NAME, SPACE FROM information_schema.INNODB_SYS_TABLES").Rows()
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	spaces := make(map[string][]uint64)
+	for rows.Next() {
+		var (
+			name  string
+			space uint64
+		)
+		if err = rows.Scan(&name, &space); err != nil {
+			return nil, err
+		}
+		// 分区名为 table#p#p0（5.7 为 #P#）
+		name, _, _ = strings.Cut(name, "#")
+		spaces[name] = append(spaces[name], space)
+	}
+	return spaces, rows.Err()
+}
+
+// exclusiveFree 判断各表的 DATA_FREE 是否可计入库汇总：共享表空间（系统表空间、通用表空间）中的表
+// 报告的是整个表空间的空闲空间，重复累加会放大数倍；非 InnoDB 表按表计算。
+// 无法得知表空间时保守地不计入
+func exclusiveFree(tables []tableSize, spaces map[string][]uint64) []bool {
+	users := make(map[uint64]int)
+	for _, list := range spaces {
+		for _, space := range slices.Compact(slices.Sorted(slices.Values(list))) {
+			users[space]++
+		}
+	}
+
+	own := make([]bool, len(tables))
+	for i, t := range tables {
+		if !strings.EqualFold(t.engine, "InnoDB") {
+			own[i] = true
+			continue
+		}
+		list := spaces[t.schema+"/"+t.name]
+		own[i] = len(list) > 0
+		for _, space := range list {
+			if space == 0 || users[space] > 1 {
+				own[i] = false
+			}
+		}
+	}
+	return own
+}
+
+// growth 相对上次采集的增长率，字节/天
+func (c *tablesCollector) growth(key string, size uint64, now time.Time) (float64, bool) {
+	last, ok := c.prev[key]
+	elapsed := now.Sub(c.prevAt)
+	if !ok || elapsed <= 0 {
+		return 0, false
+	}
+	return util.ToDouble((float64(size) - float64(last)) * float64(24*time.Hour) / float64(elapsed)), true
+}
+
+// capacity 按窗口内最早与当前的合计大小计算增长趋势，本机实例据此预测文件系统写满的天数
+func (c *tablesCollector) capacity(ctx context.Context, datadir string, total uint64, now time.Time) []Sample {
+	c.history = append(c.history, sizePoint{at: now, size: total})
+	for len(c.history) > 1 && now.Sub(c.history[0].at) > growthWindow {
+		c.history = c.history[1:]
+	}
+
+	labels := map[string]string{"datadir": datadir}
+	samples := []Sample{{Name: datadirPrefix + "data_size", Value: float64(total), Labels: labels}}
+
+	var rate float64
+	if oldest := c.history[0]; now.After(oldest.at) {
+		rate = (float64(total) - float64(oldest.size)) * float64(24*time.Hour) / float64(now.Sub(oldest.at))
+		samples = append(samples, Sample{Name: datadirPrefix + "growth_rate", Value: util.ToDouble(rate), Labels: labels})
+	}
+
+	if !c.local || datadir == "" {
+		return samples
+	}
+	usage, err := disk.UsageWithContext(ctx, datadir)
+	if err != nil {
+		c.logger.Warn("读取数据目录所在文件系统失败", zap.String("datadir", datadir), zap.Error(err))
+		return samples
+	}
+	samples = append(samples,
+		Sample{Name: datadirPrefix + "fs_total", Value: float64(usage.Total), Labels: labels},
+		Sample{Name: datadirPrefix + "fs_free", Value: float64(usage.Free), Labels: labels},
+	)
+	if rate > 0 {
+		days := util.ToDouble(float64(usage.Free) / rate)
+		c.logger.Info("数据目录容量预测", zap.String("datadir", datadir), zap.Float64("GrowthRate", rate), zap.Float64("DaysUntilFull", days))
+		samples = append(samples, Sample{Name: datadirPrefix + "days_until_full", Value: days, Labels: labels})
+	}
+	return samples
+}
@@ -0,0 +1,38 @@
+package monitor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExclusiveFree(t *testing.T) {
+	tables := []tableSize{
+		{schema: "shop", name: "orders", engine: "InnoDB", dataFree: 4 << 20},  // 独立表空间
+		{schema: "shop", name: "items", engine: "InnoDB", dataFree: 64 << 20},  // 系统表空间
+		{schema: "shop", name: "logs", engine: "MyISAM", dataFree: 1 << 20},    // 非 InnoDB
+		{schema: "crm", name: "users", engine: "InnoDB", dataFree: 32 << 20},   // 通用表空间
+		{schema: "crm", name: "events", engine: "InnoDB", dataFree: 32 << 20},  // 通用表空间
+		{schema: "crm", name: "history", engine: "InnoDB", dataFree: 8 << 20},  // 分区表，各分区独立表空间
+		{schema: "crm", name: "unknown", engine: "innodb", dataFree: 16 << 20}, // 未找到表空间
+		{schema: "crm", name: "mixed", engine: "InnoDB", dataFree: 32 << 20},   // 部分分区在通用表空间
+	}
+	spaces := map[string][]uint64{
+		"shop/orders":  {12},
+		"shop/items":   {0},
+		"crm/users":    {30},
+		"crm/events":   {30},
+		"crm/history":  {41, 42, 43},
+		"crm/mixed":    {44, 30},
+		"mysql/server": {1},
+	}
+	want := []bool{true, false, true, false, false, true, false, false}
+	if got := exclusiveFree(tables, spaces); !reflect.DeepEqual(got, want) {
+		t.Errorf("exclusiveFree = %v, want %v", got, want)
+	}
+
+	// 读取表空间失败时只计入非 InnoDB 表
+	want = []bool{false, false, true, false, false, false, false, false}
+	if got := exclusiveFree(tables, nil); !reflect.DeepEqual(got, want) {
+		t.Errorf("exclusiveFree(nil) = %v, want %v", got, want)
+	}
+}
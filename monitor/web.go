@@ -2,18 +2,14 @@ package monitor
 
 import (
 	"context"
-	"fmt"
-	"github.com/go-ping/ping"
 	"github.com/shirou/gopsutil/v4/cpu"
 	"github.com/shirou/gopsutil/v4/disk"
 	"github.com/shirou/gopsutil/v4/load"
 	"github.com/shirou/gopsutil/v4/mem"
-	"github.com/shirou/gopsutil/v4/net"
 	"github.com/solvewer/server-monitor/configuration"
 	"github.com/solvewer/server-monitor/util"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
-	"sync"
 	"time"
 )
 
@@ -28,8 +24,8 @@ type ServerMonitor struct {
 	DiskUsage    float64   `gorm:"column:disk_usage"`
 	DiskTotal    uint64    `gorm:"column:disk_total"`
 	DiskUsed     uint64    `gorm:"column:disk_used"`
-	SentSpeed    float64   `gorm:"column:sent_speed"`
-	ReceiveSpeed float64   `gorm:"column:receive_speed"`
+	SentSpeed    float64   `gorm:"column:sent_speed"`    // 统计周期内的发送量，MB
+	ReceiveSpeed float64   `gorm:"column:receive_speed"` // 统计周期内的接收量，MB
 	AvgRtt       float64   `gorm:"column:avg_rtt"`
 	PacketLoss   float64   `gorm:"column:packet_loss"`
 	Node         int       `gorm:"column:node;primaryKey"`
@@ -37,46 +33,103 @@ type ServerMonitor struct {
 }
 
 var (
-	db        *gorm.DB
-	config    *configuration.Config
-	wg        sync.WaitGroup
-	lastRecv  uint64
-	lastSent  uint64
-	webLogger *zap.Logger
+	db          *gorm.DB
+	config      *configuration.Config
+	webLogger   *zap.Logger
+	webRegistry = NewRegistry()
+	webSinks    *Fanout
 )
 
 func init() {
+	registerMainTable("server_monitor", ServerMonitor{})
+
+	webRegistry.Register(
+		pressureCollector{},
+		cpuCollector{},
+		memCollector{},
+		swapCollector{},
+		diskCollector{},
+	)
+}
+
+// setup 读取已加载的配置与数据库连接，需在 configuration.Load 之后调用
+func setup() {
 	db = configuration.GetDb()
 	config = configuration.GetConfig()
 }
 
-func calc(t time.Time) *ServerMonitor {
+func calc(t time.Time) *Snapshot {
 	monitor := new(ServerMonitor)
 
-	wg.Add(7)
-	monitor.route()
-	wg.Wait()
+	samples := webRegistry.Collect(context.Background(), t, webLogger)
+	fill(monitor, "", unlabelled(samples), nil)
 
-	monitor.CreatedAt = t.Truncate(time.Minute)
+	monitor.CreatedAt = t.Truncate(config.Interval)
 	webLogger.Info("入表时间", zap.Time("时间", monitor.CreatedAt))
-	monitor.Node = config.WebNode
-	return monitor
+	monitor.Node = config.Node
+
+	// 主表之外，带标签的样本按类别写入各自的子表
+	records := []Record{{Table: "server_monitor", Value: monitor}}
+	records = append(records, childRecords[DiskMonitor](diskTable, diskPrefix, "mountpoint", samples, monitor.Node, monitor.CreatedAt)...)
+	records = append(records, childRecords[NetMonitor](netTable, netPrefix, "interface", samples, monitor.Node, monitor.CreatedAt)...)
+	records = append(records, childRecords[DiskIOMonitor](diskIOTable, diskIOPrefix, "device", samples, monitor.Node, monitor.CreatedAt)...)
+	records = append(records, childRecords[PingMonitor](pingTable, pingPrefix, "target", samples, monitor.Node, monitor.CreatedAt)...)
+	records = append(records, childRecords[ProbeMonitor](probeTable, probePrefix, "probe", samples, monitor.Node, monitor.CreatedAt)...)
+	records = append(records, childRecords[DnsMonitor](dnsTable, dnsPrefix, "query", samples, monitor.Node, monitor.CreatedAt)...)
+	records = append(records, childRecords[CertMonitor](certTable, certPrefix, "cert", samples, monitor.Node, monitor.CreatedAt)...)
+
+	return &Snapshot{
+		Source:  "web",
+		Node:    monitor.Node,
+		Time:    monitor.CreatedAt,
+		Samples: samples,
+		Records: records,
+	}
 }
 
 func Start() {
+	setup()
 	webLogger = configuration.GetLogger(configuration.WebLogName)
-	// Step 1: 计算距离下一个整分钟的时间
+	probes, err := newProbeCollector(config.Collectors.Probes)
+	if err != nil {
+		webLogger.Error("初始化探测失败", zap.Error(err))
+		panic(err)
+	}
+	dns, err := newDNSCollector(config.Collectors.DNS)
+	if err != nil {
+		webLogger.Error("初始化DNS探测失败", zap.Error(err))
+		panic(err)
+	}
+	webRegistry.Register(
+		newPartitionCollector(config.Collectors.Partitions),
+		newNetCollector(config.Collectors.Interfaces),
+		newDiskIOCollector(config.Collectors.Devices),
+		newPingCollector(config.Collectors.Ping),
+		probes,
+		newCertCollector(config.Collectors.Certs),
+		dns,
+	)
+	webRegistry.Configure(config.Collectors.Enabled, config.Collectors.Disabled, config.Collectors.Intervals)
+	webLogger.Info("已启用的采集器", zap.Strings("collectors", webRegistry.Names()))
+
+	if webSinks, err = newSinks("web", config.Sinks.Enabled, webLogger); err != nil {
+		webLogger.Error("初始化存储失败", zap.Error(err))
+		panic(err)
+	}
+	webLogger.Info("已启用的存储", zap.Strings("sinks", webSinks.Names()))
+
+	// Step 1: 计算距离下一个统计周期的时间
 	now := time.Now()
 	webLogger.Info("服务器开始监控时间", zap.Time("开始监控", now))
 
-	next := now.Truncate(time.Minute).Add(time.Minute + time.Second*30)
-	time.Sleep(time.Until(next)) // 等待直到下一个30秒时刻
+	next := now.Truncate(config.Interval).Add(config.Interval + config.Interval/2)
+	time.Sleep(time.Until(next)) // 等待直到下一个周期的中间时刻（默认为下一分钟的30秒）
 
 	webLogger.Info("开始统计时间", zap.Time("开始统计", time.Now()))
 	run(next)
 	webLogger.Info("统计结束时间", zap.Time("结束统计", time.Now()))
 
-	ticker := time.NewTicker(time.Minute)
+	ticker := time.NewTicker(config.Interval)
 	defer ticker.Stop() // 确保释放资源
 
 	for now = range ticker.C {
@@ -87,166 +140,109 @@ func Start() {
 }
 
 func run(t time.Time) {
-	monitor := calc(t)
-	monitor.save()
+	snapshot := calc(t)
+	if err := webSinks.Write(context.Background(), snapshot); err != nil {
+		webLogger.Error("新增数据失败", zap.Error(err))
+	}
 }
 
-func (monitor *ServerMonitor) save() {
-	ctx := context.Background()
-	err := gorm.G[ServerMonitor](db).Table("server_monitor").Create(ctx, monitor)
+// 1. 压力（系统负载 / CPU 核数）= 1分钟平均负载 / CPU核数
+type pressureCollector struct{}
+
+func (pressureCollector) Name() string            { return "pressure" }
+func (pressureCollector) Interval() time.Duration { return 0 }
+
+func (pressureCollector) Collect(ctx context.Context) ([]Sample, error) {
+	loadAvg, err := load.AvgWithContext(ctx)
 	if err != nil {
-		webLogger.Error("新增数据失败", zap.Error(err))
+		return nil, err
 	}
+	cpuCount, err := cpu.CountsWithContext(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	pressure := util.ToDouble(loadAvg.Load1 / float64(cpuCount))
+	webLogger.Info("系统压力", zap.Float64("Pressure", pressure))
 
-	sql := db.ToSQL(func(tx *gorm.DB) *gorm.DB {
-		return tx.Model(&ServerMonitor{}).Create(monitor)
-	})
+	// 系统负载（Load1）
+	loadAvg1 := util.ToDouble(loadAvg.Load1)
+	webLogger.Info("系统负载", zap.Float64("LoadAvg", loadAvg1))
 
-	webLogger.Info("sql及插入的行数",
-		zap.String("sql", sql),
-		zap.Int64("rows", db.RowsAffected),
-	)
+	return []Sample{gauge("pressure", pressure), gauge("load_avg", loadAvg1)}, nil
 }
 
-func (monitor *ServerMonitor) route() {
-	//1. 压力（系统负载 / CPU 核数）= 1分钟平均负载 / CPU核数
-	go func() {
-		defer wg.Done()
-		monitor.pressure()
-	}()
-
-	// 2. CPU 使用率
-	go func() {
-		defer wg.Done()
-		monitor.cpu()
-	}()
-
-	// 3. 内存使用率
-	go func() {
-		defer wg.Done()
-		monitor.mem()
-	}()
-
-	// 4. 交换分区使用率
-	go func() {
-		defer wg.Done()
-		monitor.swap()
-	}()
-
-	// 5. 根分区使用率
-	go func() {
-		defer wg.Done()
-		monitor.disk()
-	}()
-
-	// 6 网络使用量（当前瞬时的发送接收字节数）
-	go func() {
-		defer wg.Done()
-		monitor.byte()
-	}()
-
-	// 7 网络流量
-	go func() {
-		defer wg.Done()
-		monitor.net()
-	}()
-}
-
-func (monitor *ServerMonitor) pressure() {
-	loadAvg, _ := load.Avg()
-	cpuCount, _ := cpu.Counts(true)
-	monitor.Pressure = util.ToDouble(loadAvg.Load1 / float64(cpuCount))
-	webLogger.Info("系统压力", zap.Float64("Pressure", monitor.Pressure))
+// 2. CPU 使用率
+type cpuCollector struct{}
 
-	// 3. 系统负载（Load1）
-	monitor.LoadAvg = util.ToDouble(loadAvg.Load1)
-	webLogger.Info("系统负载", zap.Float64("LoadAvg", monitor.LoadAvg))
-}
+func (cpuCollector) Name() string            { return "cpu" }
+func (cpuCollector) Interval() time.Duration { return 0 }
 
-func (monitor *ServerMonitor) cpu() {
-	cpuPercent, _ := cpu.Percent(time.Second, false) // 采样一秒
-	monitor.CpuUsage = util.ToDouble(cpuPercent[0])
-	webLogger.Info("cpu使用率", zap.Float64("CpuUsage", monitor.CpuUsage))
+func (cpuCollector) Collect(ctx context.Context) ([]Sample, error) {
+	cpuPercent, err := cpu.PercentWithContext(ctx, time.Second, false) // 采样一秒
+	if err != nil {
+		return nil, err
+	}
+	cpuUsage := util.ToDouble(cpuPercent[0])
+	webLogger.Info("cpu使用率", zap.Float64("CpuUsage", cpuUsage))
 
+	return []Sample{gauge("cpu_usage", cpuUsage)}, nil
 }
 
-func (monitor *ServerMonitor) mem() {
-	vmem, _ := mem.VirtualMemory()
-	monitor.MemUsage = util.ToDouble(vmem.UsedPercent)
-	monitor.MemTotal = util.ToGbInt64(vmem.Total)
-	monitor.MemUsed = util.ToGbInt64(vmem.Used)
-	webLogger.Info("内存使用情况", zap.Float64("CpuUsage", monitor.CpuUsage))
-}
+// 3. 内存使用率
+type memCollector struct{}
 
-func (monitor *ServerMonitor) swap() {
-	swap, _ := mem.SwapMemory()
-	monitor.SwapUsage = util.ToDouble(swap.UsedPercent)
-	webLogger.Info("交换区使用情况", zap.Float64("SwapUsage", monitor.SwapUsage))
+func (memCollector) Name() string            { return "mem" }
+func (memCollector) Interval() time.Duration { return 0 }
 
+func (memCollector) Collect(ctx context.Context) ([]Sample, error) {
+	vmem, err := mem.VirtualMemoryWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	memUsage := util.ToDouble(vmem.UsedPercent)
+	webLogger.Info("内存使用情况", zap.Float64("MemUsage", memUsage))
+
+	return []Sample{
+		gauge("mem_usage", memUsage),
+		gauge("mem_total", float64(util.ToGbInt64(vmem.Total))),
+		gauge("mem_used", float64(util.ToGbInt64(vmem.Used))),
+	}, nil
 }
 
-func (monitor *ServerMonitor) disk() {
-	diskUsage, _ := disk.Usage("/")
-	monitor.DiskUsage = util.ToDouble(diskUsage.UsedPercent)
-	monitor.DiskTotal = util.ToGbInt64(diskUsage.Total)
-	monitor.DiskUsed = util.ToGbInt64(diskUsage.Used)
-	webLogger.Info("磁盘使用情况", zap.Float64("DiskUsage", monitor.DiskUsage))
-}
+// 4. 交换分区使用率
+type swapCollector struct{}
 
-func (monitor *ServerMonitor) byte() {
-	ioStats, _ := net.IOCounters(false)
-	currentRecv := ioStats[0].BytesRecv
-	currentSent := ioStats[0].BytesSent
-	if lastRecv == 0 {
-		monitor.ReceiveSpeed = 0
-	} else {
-		monitor.ReceiveSpeed = util.ToDouble(util.ToMbFloat(currentRecv - lastRecv))
-	}
-	if lastSent == 0 {
-		monitor.SentSpeed = 0
-	} else {
-		monitor.SentSpeed = util.ToDouble(util.ToMbFloat(currentSent - lastSent))
-	}
-	lastRecv = currentRecv
-	lastSent = currentSent
-	webLogger.Info("磁盘IO", zap.Float64("ReceiveSpeed", monitor.ReceiveSpeed), zap.Float64("SentSpeed", monitor.SentSpeed))
-}
+func (swapCollector) Name() string            { return "swap" }
+func (swapCollector) Interval() time.Duration { return 0 }
 
-func (monitor *ServerMonitor) net() {
-	pinger, err := ping.NewPinger("8.8.8.8") // Google DNS
+func (swapCollector) Collect(ctx context.Context) ([]Sample, error) {
+	swap, err := mem.SwapMemoryWithContext(ctx)
 	if err != nil {
-		fmt.Println("初始化ping报错：", err)
-		webLogger.Error("初始化ping报错：", zap.Error(err))
-	}
-	pinger.Count = 5                 // 一次发送 5 个 ping
-	pinger.Interval = time.Second    // 每秒一个
-	pinger.Timeout = 6 * time.Second // 最长运行时间
-	pinger.SetPrivileged(true)       // 使用原始 socket（需要 root 权限）
-
-	// 可选：注册回调
-	pinger.OnRecv = func(pkt *ping.Packet) {
-		fmt.Printf("Reply from %s: time=%v\n", pkt.IPAddr, pkt.Rtt)
-		webLogger.Info("ping响应", zap.String("IPAddr", pkt.IPAddr.String()), zap.Int64("Rtt", int64(pkt.Rtt)))
-	}
-	pinger.OnFinish = func(stats *ping.Statistics) {
-		//spew.Dump(stats)
-		//os.Exit(1)
-		fmt.Printf("\n--- %s ping statistics ---\n", stats.Addr)
-		fmt.Printf("Packets: Sent = %d, Received = %d, Lost = %d (%.1f%% loss)\n",
-			stats.PacketsSent, stats.PacketsRecv, stats.PacketsSent-stats.PacketsRecv, stats.PacketLoss)
-		fmt.Printf("RTT: min = %v, avg = %v, max = %v, stddev = %v\n",
-			stats.MinRtt, stats.AvgRtt, stats.MaxRtt, stats.StdDevRtt)
-
-		monitor.AvgRtt = util.ToDouble(float64(int(stats.AvgRtt)) / 1000000)
-		monitor.PacketLoss = util.ToDouble(stats.PacketLoss)
-		webLogger.Info("ping数据", zap.String("IPAddr", stats.Addr), zap.Float64("PacketLoss", monitor.PacketLoss), zap.Float64("AvgRtt", monitor.AvgRtt))
+		return nil, err
 	}
+	swapUsage := util.ToDouble(swap.UsedPercent)
+	webLogger.Info("交换区使用情况", zap.Float64("SwapUsage", swapUsage))
 
-	fmt.Println("PING 8.8.8.8:")
-	err = pinger.Run() // 阻塞执行
-	if err != nil {
-		fmt.Println("ping运行时报错：", err)
-		webLogger.Error("ping运行时报错：", zap.Error(err))
+	return []Sample{gauge("swap_usage", swapUsage)}, nil
+}
+
+// 5. 根分区使用率
+type diskCollector struct{}
 
+func (diskCollector) Name() string            { return "disk" }
+func (diskCollector) Interval() time.Duration { return 0 }
+
+func (diskCollector) Collect(ctx context.Context) ([]Sample, error) {
+	diskUsage, err := disk.UsageWithContext(ctx, "/")
+	if err != nil {
+		return nil, err
 	}
+	usage := util.ToDouble(diskUsage.UsedPercent)
+	webLogger.Info("磁盘使用情况", zap.Float64("DiskUsage", usage))
+
+	return []Sample{
+		gauge("disk_usage", usage),
+		gauge("disk_total", float64(util.ToGbInt64(diskUsage.Total))),
+		gauge("disk_used", float64(util.ToGbInt64(diskUsage.Used))),
+	}, nil
 }
@@ -0,0 +1,157 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/solvewer/server-monitor/alert"
+	"github.com/solvewer/server-monitor/configuration"
+	"go.uber.org/zap"
+)
+
+const (
+	TypeDingTalk = "dingtalk"
+	TypeWeCom    = "wecom"
+	TypeFeishu   = "feishu"
+	TypeWebhook  = "webhook"
+	TypeSMTP     = "smtp"
+)
+
+// defaultTemplate 默认消息模板，按事件逐条渲染后以空行拼接
+const defaultTemplate = `{{if eq .State "firing"}}【告警】{{else if .Stale}}【恢复（已停止上报）】{{else}}【恢复】{{end}}{{.Rule}}
+级别：{{.Severity}}
+节点：{{.Node}}（{{.Source}}）
+指标：{{.Metric}}{{labels .Labels}}
+当前值：{{value .Value}}，阈值：{{value .Threshold}}
+开始时间：{{datetime .Since}}{{if eq .State "resolved"}}
+恢复时间：{{datetime .At}}{{end}}{{if .Summary}}
+说明：{{.Summary}}{{end}}`
+
+var funcs = template.FuncMap{
+	"labels": func(labels map[string]string) string {
+		if len(labels) == 0 {
+			return ""
+		}
+		keys := make([]string, 0, len(labels))
+		for k := range labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		pairs := make([]string, 0, len(keys))
+		for _, k := range keys {
+			pairs = append(pairs, k+"="+labels[k])
+		}
+		return "{" + strings.Join(pairs, ",") + "}"
+	},
+	"value": func(v float64) string {
+		return fmt.Sprintf("%.2f", v)
+	},
+	"datetime": func(t time.Time) string {
+		return t.Format("2006-01-02 15:04:05")
+	},
+}
+
+// New 根据配置创建通知渠道
+func New(cfg configuration.NotifierConfig, logger *zap.Logger) (alert.Notifier, error) {
+	switch cfg.Type {
+	case TypeDingTalk, TypeWeCom, TypeFeishu, TypeWebhook:
+		return newWebhook(cfg, logger)
+	case TypeSMTP:
+		return newMailer(cfg, logger)
+	default:
+		return nil, fmt.Errorf("未知的通知类型: %s", cfg.Type)
+	}
+}
+
+// renderer 消息模板
+type renderer struct {
+	tpl *template.Template
+}
+
+func newRenderer(name, text string) (*renderer, error) {
+	if text == "" {
+		text = defaultTemplate
+	}
+	tpl, err := template.New(name).Funcs(funcs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("通知 %s 消息模板无效: %w", name, err)
+	}
+	return &renderer{tpl: tpl}, nil
+}
+
+func (r *renderer) render(events []alert.Event) (string, error) {
+	parts := make([]string, 0, len(events))
+	for _, event := range events {
+		var buf bytes.Buffer
+		if err := r.tpl.Execute(&buf, event); err != nil {
+			return "", err
+		}
+		parts = append(parts, buf.String())
+	}
+	return strings.Join(parts, "\n\n"), nil
+}
+
+// retry 失败后按指数退避重试
+func retry(ctx context.Context, retries int, interval time.Duration, fn func() error) error {
+	var err error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		if attempt == retries {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w（最后一次错误：%v）", ctx.Err(), err)
+		case <-time.After(interval << attempt):
+		}
+	}
+	return fmt.Errorf("重试%d次后仍失败: %w", retries, err)
+}
+
+// limiter 令牌桶限流，rate 为每分钟允许发送的消息数，0 表示不限流
+type limiter struct {
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newLimiter(perMinute int) *limiter {
+	return &limiter{rate: float64(perMinute), tokens: float64(perMinute), last: time.Now()}
+}
+
+// Wait 阻塞直到获得一个令牌或 ctx 结束
+func (l *limiter) Wait(ctx context.Context) error {
+	if l.rate <= 0 {
+		return nil
+	}
+
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		l.tokens = min(l.rate, l.tokens+now.Sub(l.last).Minutes()*l.rate)
+		l.last = now
+		if l.tokens >= 1 {
+			l.tokens--
+			l.mu.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - l.tokens) / l.rate * float64(time.Minute))
+		l.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
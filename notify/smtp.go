@@ -0,0 +1,279 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/solvewer/server-monitor/alert"
+	"github.com/solvewer/server-monitor/configuration"
+	"go.uber.org/zap"
+)
+
+const (
+	smtpStartTLS = "starttls"
+	smtpTLS      = "tls"
+	smtpNone     = "none"
+)
+
+const defaultSubject = `[server-monitor] {{if eq (len .) 1}}{{with index . 0}}{{if eq .State "firing"}}告警{{else}}恢复{{end}} {{.Rule}} 节点{{.Node}}{{end}}{{else}}{{len .}} 条告警事件{{end}}`
+
+// Mailer SMTP 邮件通知，支持逐条发送与周期摘要
+type Mailer struct {
+	cfg      configuration.NotifierConfig
+	renderer *renderer
+	subject  *template.Template
+	limiter  *limiter
+	logger   *zap.Logger
+
+	mu        sync.Mutex
+	pending   []alert.Event // 摘要模式下等待发送的事件
+	lastFlush time.Time
+}
+
+func newMailer(cfg configuration.NotifierConfig, logger *zap.Logger) (*Mailer, error) {
+	smtpCfg := &cfg.SMTP
+	if smtpCfg.Host == "" || smtpCfg.From == "" || len(smtpCfg.To) == 0 {
+		return nil, fmt.Errorf("通知 %s 需配置 smtp.host、smtp.from 与 smtp.to", cfg.Name)
+	}
+	if smtpCfg.TLS == "" {
+		smtpCfg.TLS = smtpStartTLS
+	}
+	switch smtpCfg.TLS {
+	case smtpStartTLS, smtpNone:
+		if smtpCfg.Port == 0 {
+			smtpCfg.Port = 25
+		}
+	case smtpTLS:
+		if smtpCfg.Port == 0 {
+			smtpCfg.Port = 465
+		}
+	default:
+		return nil, fmt.Errorf("通知 %s 的 smtp.tls 无效: %s", cfg.Name, smtpCfg.TLS)
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	if cfg.RetryInterval <= 0 {
+		cfg.RetryInterval = 5 * time.Second
+	}
+
+	r, err := newRenderer(cfg.Name, cfg.Template)
+	if err != nil {
+		return nil, err
+	}
+	subjectText := smtpCfg.Subject
+	if subjectText == "" {
+		subjectText = defaultSubject
+	}
+	subject, err := template.New(cfg.Name + "-subject").Funcs(funcs).Parse(subjectText)
+	if err != nil {
+		return nil, fmt.Errorf("通知 %s 主题模板无效: %w", cfg.Name, err)
+	}
+
+	return &Mailer{
+		cfg:       cfg,
+		renderer:  r,
+		subject:   subject,
+		limiter:   newLimiter(cfg.RateLimit),
+		logger:    logger,
+		lastFlush: time.Now(),
+	}, nil
+}
+
+func (m *Mailer) Name() string {
+	return m.cfg.Name
+}
+
+// Notify 逐条模式直接发送；摘要模式只缓存，等待 Cycle 按周期发送
+func (m *Mailer) Notify(ctx context.Context, events []alert.Event) error {
+	if m.cfg.SMTP.Digest > 0 {
+		m.mu.Lock()
+		m.pending = append(m.pending, events...)
+		m.mu.Unlock()
+		return nil
+	}
+
+	body, err := m.renderer.render(events)
+	if err != nil {
+		return err
+	}
+	return m.deliver(ctx, events, body)
+}
+
+// Cycle 每个统计周期调用，到达摘要间隔且有新事件时发送摘要
+func (m *Mailer) Cycle(ctx context.Context, t time.Time, firing []alert.Event) error {
+	if m.cfg.SMTP.Digest <= 0 {
+		return nil
+	}
+
+	m.mu.Lock()
+	if t.Sub(m.lastFlush) < m.cfg.SMTP.Digest || len(m.pending) == 0 {
+		m.mu.Unlock()
+		return nil
+	}
+	events := m.pending
+	m.pending = nil
+	m.lastFlush = t
+	m.mu.Unlock()
+
+	body, err := m.digest(events, firing)
+	if err == nil {
+		err = m.deliver(ctx, events, body)
+	}
+	if err != nil {
+		// 发送失败放回队列，下个周期再试
+		m.mu.Lock()
+		m.pending = append(events, m.pending...)
+		m.mu.Unlock()
+	}
+	return err
+}
+
+// digest 摘要正文：期间内的状态变化 + 当前仍在触发的告警
+func (m *Mailer) digest(events, firing []alert.Event) (string, error) {
+	changes, err := m.renderer.render(events)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "告警摘要（最近 %s）\n\n", m.cfg.SMTP.Digest)
+	fmt.Fprintf(&b, "== 状态变化（%d 条） ==\n\n%s\n\n", len(events), changes)
+	fmt.Fprintf(&b, "== 当前仍在告警（%d 条） ==\n\n", len(firing))
+	if len(firing) > 0 {
+		current, err := m.renderer.render(firing)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(current)
+		b.WriteString("\n")
+	} else {
+		b.WriteString("无\n")
+	}
+	return b.String(), nil
+}
+
+func (m *Mailer) deliver(ctx context.Context, events []alert.Event, body string) error {
+	var subject bytes.Buffer
+	if err := m.subject.Execute(&subject, events); err != nil {
+		return err
+	}
+
+	if err := m.limiter.Wait(ctx); err != nil {
+		return fmt.Errorf("等待发送限流: %w", err)
+	}
+
+	err := retry(ctx, m.cfg.Retries, m.cfg.RetryInterval, func() error {
+		return m.send(ctx, subject.String(), body)
+	})
+	if err == nil {
+		m.logger.Info("告警邮件发送成功", zap.String("notifier", m.cfg.Name), zap.Int("events", len(events)))
+	}
+	return err
+}
+
+func (m *Mailer) send(ctx context.Context, subject, body string) error {
+	cfg := m.cfg.SMTP
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+	tlsConfig := &tls.Config{ServerName: cfg.Host, InsecureSkipVerify: cfg.InsecureSkipVerify}
+
+	ctx, cancel := context.WithTimeout(ctx, m.cfg.Timeout)
+	defer cancel()
+
+	dialer := &net.Dialer{}
+	var (
+		conn net.Conn
+		err  error
+	)
+	if cfg.TLS == smtpTLS {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if cfg.TLS == smtpStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err = client.StartTLS(tlsConfig); err != nil {
+				return fmt.Errorf("STARTTLS 失败: %w", err)
+			}
+		}
+	}
+
+	if cfg.Username != "" {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return errors.New("SMTP 服务器不支持认证")
+		}
+		if err = client.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)); err != nil {
+			return fmt.Errorf("SMTP 认证失败: %w", err)
+		}
+	}
+
+	if err = client.Mail(cfg.From); err != nil {
+		return err
+	}
+	for _, to := range cfg.To {
+		if err = client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(m.message(subject, body)); err != nil {
+		_ = w.Close()
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// message 构造 MIME 邮件，主题与正文均使用 UTF-8
+func (m *Mailer) message(subject, body string) []byte {
+	cfg := m.cfg.SMTP
+
+	var b bytes.Buffer
+	b.WriteString("From: " + cfg.From + "\r\n")
+	b.WriteString("To: " + strings.Join(cfg.To, ", ") + "\r\n")
+	b.WriteString("Subject: " + mime.BEncoding.Encode("UTF-8", subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+
+	encoded := base64.StdEncoding.EncodeToString([]byte(body))
+	for len(encoded) > 76 {
+		b.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	b.WriteString(encoded + "\r\n")
+	return b.Bytes()
+}
@@ -0,0 +1,301 @@
+package notify
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"math/big"
+	"mime"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/solvewer/server-monitor/alert"
+	"github.com/solvewer/server-monitor/configuration"
+	"go.uber.org/zap"
+)
+
+// smtpSession 本地 SMTP 桩记录的一次会话
+type smtpSession struct {
+	tls  bool
+	auth string
+	from string
+	to   []string
+	data string
+}
+
+// smtpStub 进程内的最小 SMTP 服务，可选支持 STARTTLS，前 failMail 次 MAIL 命令返回临时错误
+type smtpStub struct {
+	listener net.Listener
+	tls      *tls.Config
+
+	mu       sync.Mutex
+	failMail int
+	sessions []*smtpSession
+}
+
+func newSMTPStub(t *testing.T, startTLS bool, failMail int) *smtpStub {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpStub{listener: l, failMail: failMail}
+	if startTLS {
+		s.tls = &tls.Config{Certificates: []tls.Certificate{selfSigned(t)}}
+	}
+	t.Cleanup(func() { _ = l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpStub) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpStub) serve(conn net.Conn) {
+	defer conn.Close()
+	session := &smtpSession{}
+	text := textproto.NewConn(conn)
+	reply := func(line string) { _ = text.PrintfLine("%s", line) }
+
+	reply("220 stub ESMTP")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.Fields(line + " ")[0])
+		switch cmd {
+		case "EHLO", "HELO":
+			lines := []string{"250-stub"}
+			if s.tls != nil && !session.tls {
+				lines = append(lines, "250-STARTTLS")
+			}
+			lines = append(lines, "250 AUTH PLAIN")
+			for _, l := range lines {
+				reply(l)
+			}
+		case "STARTTLS":
+			reply("220 ready")
+			tlsConn := tls.Server(conn, s.tls)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn, session.tls = tlsConn, true
+			text = textproto.NewConn(conn)
+		case "AUTH":
+			session.auth = strings.TrimSpace(strings.TrimPrefix(line, "AUTH PLAIN"))
+			reply("235 ok")
+		case "MAIL":
+			s.mu.Lock()
+			fail := s.failMail > 0
+			s.failMail--
+			s.mu.Unlock()
+			if fail {
+				reply("451 try again later")
+				continue
+			}
+			session.from = line
+			reply("250 ok")
+		case "RCPT":
+			session.to = append(session.to, line)
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+			data, err := text.ReadDotLines()
+			if err != nil {
+				return
+			}
+			session.data = strings.Join(data, "\n")
+			s.mu.Lock()
+			s.sessions = append(s.sessions, session)
+			s.mu.Unlock()
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func (s *smtpStub) delivered() []*smtpSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*smtpSession(nil), s.sessions...)
+}
+
+func selfSigned(t *testing.T) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func newTestMailer(t *testing.T, smtpCfg configuration.SMTPConfig, retries int) *Mailer {
+	t.Helper()
+	m, err := newMailer(configuration.NotifierConfig{
+		Name: "mail", Type: TypeSMTP, Retries: retries, RetryInterval: time.Millisecond, Timeout: 5 * time.Second,
+		SMTP: smtpCfg,
+	}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// decodeMessage 解析桩收到的邮件，返回解码后的主题与正文
+func decodeMessage(t *testing.T, data string) (string, string) {
+	t.Helper()
+	header, body, ok := strings.Cut(data, "\n\n")
+	if !ok {
+		t.Fatalf("malformed message:\n%s", data)
+	}
+	var subject string
+	for _, line := range strings.Split(header, "\n") {
+		if v, ok := strings.CutPrefix(line, "Subject: "); ok {
+			decoded, err := new(mime.WordDecoder).DecodeHeader(v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			subject = decoded
+		}
+	}
+	text, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(body, "\n", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return subject, string(text)
+}
+
+func TestMailerStartTLS(t *testing.T) {
+	stub := newSMTPStub(t, true, 0)
+	m := newTestMailer(t, configuration.SMTPConfig{
+		Host: "127.0.0.1", Port: stub.port(), TLS: smtpStartTLS, InsecureSkipVerify: true,
+		Username: "monitor", Password: "secret",
+		From: "monitor@example.com", To: []string{"ops@example.com", "dba@example.com"},
+	}, 0)
+
+	if err := m.Notify(context.Background(), testEvents()); err != nil {
+		t.Fatal(err)
+	}
+
+	sessions := stub.delivered()
+	if len(sessions) != 1 {
+		t.Fatalf("sessions = %d", len(sessions))
+	}
+	session := sessions[0]
+	if !session.tls {
+		t.Error("message was not sent over STARTTLS")
+	}
+	auth, _ := base64.StdEncoding.DecodeString(session.auth)
+	if string(auth) != "\x00monitor\x00secret" {
+		t.Errorf("auth = %q", auth)
+	}
+	if session.from != "MAIL FROM:<monitor@example.com>" || len(session.to) != 2 {
+		t.Errorf("envelope = %s %v", session.from, session.to)
+	}
+
+	subject, body := decodeMessage(t, session.data)
+	if subject != "[server-monitor] 告警 cpu_high 节点1" {
+		t.Errorf("subject = %q", subject)
+	}
+	if !strings.Contains(body, "【告警】cpu_high") || !strings.Contains(body, "当前值：95.50") {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func TestMailerPlainWithRetry(t *testing.T) {
+	stub := newSMTPStub(t, false, 1)
+	m := newTestMailer(t, configuration.SMTPConfig{
+		Host: "127.0.0.1", Port: stub.port(), TLS: smtpNone,
+		From: "monitor@example.com", To: []string{"ops@example.com"},
+	}, 1)
+
+	if err := m.Notify(context.Background(), testEvents()); err != nil {
+		t.Fatal(err)
+	}
+	sessions := stub.delivered()
+	if len(sessions) != 1 {
+		t.Fatalf("sessions = %d", len(sessions))
+	}
+	if sessions[0].tls || sessions[0].auth != "" {
+		t.Errorf("plain session used tls=%v auth=%q", sessions[0].tls, sessions[0].auth)
+	}
+}
+
+func TestMailerDigest(t *testing.T) {
+	stub := newSMTPStub(t, false, 0)
+	m := newTestMailer(t, configuration.SMTPConfig{
+		Host: "127.0.0.1", Port: stub.port(), TLS: smtpNone, Digest: 10 * time.Minute,
+		From: "monitor@example.com", To: []string{"ops@example.com"},
+	}, 0)
+
+	ctx := context.Background()
+	events := testEvents()
+	if err := m.Notify(ctx, events); err != nil {
+		t.Fatal(err)
+	}
+	resolved := events[0]
+	resolved.State = alert.StateResolved
+	if err := m.Notify(ctx, []alert.Event{resolved}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 未到摘要间隔不发送
+	if err := m.Cycle(ctx, m.lastFlush.Add(time.Minute), nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(stub.delivered()) != 0 {
+		t.Fatal("digest sent before interval")
+	}
+
+	if err := m.Cycle(ctx, m.lastFlush.Add(10*time.Minute), nil); err != nil {
+		t.Fatal(err)
+	}
+	sessions := stub.delivered()
+	if len(sessions) != 1 {
+		t.Fatalf("sessions = %d", len(sessions))
+	}
+	subject, body := decodeMessage(t, sessions[0].data)
+	if subject != "[server-monitor] 2 条告警事件" {
+		t.Errorf("subject = %q", subject)
+	}
+	for _, want := range []string{"状态变化（2 条）", "【恢复】cpu_high", "当前仍在告警（0 条）"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body missing %q:\n%s", want, body)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/solvewer/server-monitor/alert"
+	"github.com/solvewer/server-monitor/configuration"
+	"go.uber.org/zap"
+)
+
+// Webhook 钉钉、企业微信、飞书机器人以及通用 JSON webhook
+type Webhook struct {
+	cfg      configuration.NotifierConfig
+	client   *http.Client
+	renderer *renderer
+	limiter  *limiter
+	logger   *zap.Logger
+}
+
+func newWebhook(cfg configuration.NotifierConfig, logger *zap.Logger) (*Webhook, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("通知 %s 未配置 url", cfg.Name)
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.RetryInterval <= 0 {
+		cfg.RetryInterval = 5 * time.Second
+	}
+
+	r, err := newRenderer(cfg.Name, cfg.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Webhook{
+		cfg:      cfg,
+		client:   &http.Client{Timeout: cfg.Timeout},
+		renderer: r,
+		limiter:  newLimiter(cfg.RateLimit),
+		logger:   logger,
+	}, nil
+}
+
+func (w *Webhook) Name() string {
+	return w.cfg.Name
+}
+
+func (w *Webhook) Notify(ctx context.Context, events []alert.Event) error {
+	text, err := w.renderer.render(events)
+	if err != nil {
+		return err
+	}
+
+	if err = w.limiter.Wait(ctx); err != nil {
+		return fmt.Errorf("等待发送限流: %w", err)
+	}
+
+	err = retry(ctx, w.cfg.Retries, w.cfg.RetryInterval, func() error {
+		return w.send(ctx, text, events)
+	})
+	if err == nil {
+		w.logger.Info("告警通知发送成功", zap.String("notifier", w.cfg.Name), zap.Int("events", len(events)))
+	}
+	return err
+}
+
+func (w *Webhook) send(ctx context.Context, text string, events []alert.Event) error {
+	target, body, err := w.payload(text, events)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	for k, v := range w.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, respBody)
+	}
+	return w.check(respBody)
+}
+
+// payload 按机器人类型构造请求地址与消息体
+func (w *Webhook) payload(text string, events []alert.Event) (string, any, error) {
+	switch w.cfg.Type {
+	case TypeDingTalk:
+		target := w.cfg.URL
+		if w.cfg.Secret != "" {
+			var err error
+			if target, err = dingTalkSign(target, w.cfg.Secret, time.Now()); err != nil {
+				return "", nil, err
+			}
+		}
+		return target, map[string]any{
+			"msgtype": "text",
+			"text":    map[string]string{"content": text},
+		}, nil
+
+	case TypeWeCom:
+		return w.cfg.URL, map[string]any{
+			"msgtype": "text",
+			"text":    map[string]string{"content": text},
+		}, nil
+
+	case TypeFeishu:
+		body := map[string]any{
+			"msg_type": "text",
+			"content":  map[string]string{"text": text},
+		}
+		if w.cfg.Secret != "" {
+			timestamp := time.Now().Unix()
+			body["timestamp"] = strconv.FormatInt(timestamp, 10)
+			body["sign"] = feishuSign(w.cfg.Secret, timestamp)
+		}
+		return w.cfg.URL, body, nil
+
+	default:
+		return w.cfg.URL, map[string]any{
+			"text":   text,
+			"events": events,
+		}, nil
+	}
+}
+
+// check 解析机器人返回的业务错误码，HTTP 200 也可能发送失败
+func (w *Webhook) check(body []byte) error {
+	if w.cfg.Type == TypeWebhook || len(body) == 0 {
+		return nil
+	}
+
+	var result struct {
+		ErrCode *int   `json:"errcode"` // 钉钉、企业微信
+		ErrMsg  string `json:"errmsg"`
+		Code    *int   `json:"code"` // 飞书
+		Msg     string `json:"msg"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("无法解析响应: %s", body)
+	}
+	if result.ErrCode != nil && *result.ErrCode != 0 {
+		return fmt.Errorf("errcode=%d errmsg=%s", *result.ErrCode, result.ErrMsg)
+	}
+	if result.Code != nil && *result.Code != 0 {
+		return fmt.Errorf("code=%d msg=%s", *result.Code, result.Msg)
+	}
+	return nil
+}
+
+// dingTalkSign 钉钉加签：HmacSHA256(timestamp+"\n"+secret) 后 base64，拼接到 url 参数
+func dingTalkSign(target, secret string, now time.Time) (string, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", err
+	}
+	if secret == "" {
+		return "", errors.New("钉钉加签 secret 为空")
+	}
+
+	timestamp := strconv.FormatInt(now.UnixMilli(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "\n" + secret))
+
+	query := u.Query()
+	query.Set("timestamp", timestamp)
+	query.Set("sign", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// feishuSign 飞书签名：以 timestamp+"\n"+secret 为密钥对空串做 HmacSHA256 后 base64
+func feishuSign(secret string, timestamp int64) string {
+	mac := hmac.New(sha256.New, []byte(strconv.FormatInt(timestamp, 10)+"\n"+secret))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,183 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/solvewer/server-monitor/alert"
+	"github.com/solvewer/server-monitor/configuration"
+	"go.uber.org/zap"
+)
+
+// robot 本地模拟的机器人接口，按顺序返回预设的响应
+type robot struct {
+	mu        sync.Mutex
+	responses []func(w http.ResponseWriter)
+	requests  []*http.Request
+	bodies    []map[string]any
+}
+
+func (r *robot) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	data, _ := io.ReadAll(req.Body)
+	var body map[string]any
+	_ = json.Unmarshal(data, &body)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, body)
+	if len(r.responses) == 0 {
+		_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+		return
+	}
+	respond := r.responses[0]
+	r.responses = r.responses[1:]
+	respond(w)
+}
+
+func testEvents() []alert.Event {
+	at := time.Date(2024, 1, 1, 10, 0, 0, 0, time.Local)
+	return []alert.Event{{
+		Rule: "cpu_high", Severity: "critical", State: alert.StateFiring, Source: "web", Node: 1,
+		Metric: "cpu_usage", Value: 95.5, Threshold: 90, Since: at, At: at,
+	}}
+}
+
+func newTestWebhook(t *testing.T, typ, url, secret string, retries int) *Webhook {
+	t.Helper()
+	w, err := newWebhook(configuration.NotifierConfig{
+		Name: typ, Type: typ, URL: url, Secret: secret,
+		Retries: retries, RetryInterval: time.Millisecond,
+		Headers: map[string]string{"X-Token": "abc"},
+	}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func TestDingTalkSignedPayload(t *testing.T) {
+	r := &robot{}
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	w := newTestWebhook(t, TypeDingTalk, server.URL+"/robot/send?access_token=xxx", "SECret", 0)
+	if err := w.Notify(context.Background(), testEvents()); err != nil {
+		t.Fatal(err)
+	}
+
+	req, body := r.requests[0], r.bodies[0]
+	query := req.URL.Query()
+	if query.Get("access_token") != "xxx" {
+		t.Errorf("access_token lost: %s", req.URL.RawQuery)
+	}
+	mac := hmac.New(sha256.New, []byte("SECret"))
+	mac.Write([]byte(query.Get("timestamp") + "\nSECret"))
+	if want := base64.StdEncoding.EncodeToString(mac.Sum(nil)); query.Get("sign") != want {
+		t.Errorf("sign = %s, want %s", query.Get("sign"), want)
+	}
+	if ms, err := strconv.ParseInt(query.Get("timestamp"), 10, 64); err != nil || time.Since(time.UnixMilli(ms)) > time.Minute {
+		t.Errorf("timestamp = %s", query.Get("timestamp"))
+	}
+
+	if body["msgtype"] != "text" {
+		t.Errorf("msgtype = %v", body["msgtype"])
+	}
+	content := body["text"].(map[string]any)["content"].(string)
+	for _, want := range []string{"【告警】cpu_high", "级别：critical", "当前值：95.50，阈值：90.00"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("content missing %q:\n%s", want, content)
+		}
+	}
+	if req.Header.Get("X-Token") != "abc" || !strings.HasPrefix(req.Header.Get("Content-Type"), "application/json") {
+		t.Errorf("headers = %v", req.Header)
+	}
+}
+
+func TestFeishuSignedPayload(t *testing.T) {
+	r := &robot{}
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	w := newTestWebhook(t, TypeFeishu, server.URL, "feishu-secret", 0)
+	if err := w.Notify(context.Background(), testEvents()); err != nil {
+		t.Fatal(err)
+	}
+
+	body := r.bodies[0]
+	if body["msg_type"] != "text" || !strings.Contains(body["content"].(map[string]any)["text"].(string), "cpu_high") {
+		t.Errorf("body = %v", body)
+	}
+	timestamp := body["timestamp"].(string)
+	mac := hmac.New(sha256.New, []byte(timestamp+"\nfeishu-secret"))
+	if want := base64.StdEncoding.EncodeToString(mac.Sum(nil)); body["sign"] != want {
+		t.Errorf("sign = %v, want %s", body["sign"], want)
+	}
+}
+
+func TestGenericWebhookPayload(t *testing.T) {
+	r := &robot{}
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	w := newTestWebhook(t, TypeWebhook, server.URL, "", 0)
+	if err := w.Notify(context.Background(), testEvents()); err != nil {
+		t.Fatal(err)
+	}
+
+	events := r.bodies[0]["events"].([]any)
+	event := events[0].(map[string]any)
+	if event["rule"] != "cpu_high" || event["state"] != "firing" || event["value"] != 95.5 {
+		t.Errorf("event = %v", event)
+	}
+	if _, ok := r.bodies[0]["text"].(string); !ok {
+		t.Errorf("text missing: %v", r.bodies[0])
+	}
+}
+
+func TestWebhookRetry(t *testing.T) {
+	r := &robot{responses: []func(w http.ResponseWriter){
+		func(w http.ResponseWriter) { w.WriteHeader(http.StatusBadGateway) },
+		// HTTP 200 但业务错误码非0，同样需要重试
+		func(w http.ResponseWriter) { _, _ = w.Write([]byte(`{"errcode":130101,"errmsg":"send too fast"}`)) },
+	}}
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	w := newTestWebhook(t, TypeDingTalk, server.URL, "", 2)
+	if err := w.Notify(context.Background(), testEvents()); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.requests) != 3 {
+		t.Fatalf("requests = %d, want 3", len(r.requests))
+	}
+}
+
+func TestWebhookRetryExhausted(t *testing.T) {
+	r := &robot{responses: []func(w http.ResponseWriter){
+		func(w http.ResponseWriter) { _, _ = w.Write([]byte(`{"code":19021,"msg":"sign match fail"}`)) },
+		func(w http.ResponseWriter) { _, _ = w.Write([]byte(`{"code":19021,"msg":"sign match fail"}`)) },
+	}}
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	w := newTestWebhook(t, TypeFeishu, server.URL, "", 1)
+	err := w.Notify(context.Background(), testEvents())
+	if err == nil || !strings.Contains(err.Error(), "code=19021") {
+		t.Fatalf("err = %v", err)
+	}
+	if len(r.requests) != 2 {
+		t.Fatalf("requests = %d, want 2", len(r.requests))
+	}
+}
@@ -2,8 +2,8 @@ package util
 
 import (
 	"context"
-	"fmt"
 	"math"
+	"strings"
 	"time"
 )
 
@@ -16,7 +16,6 @@ func ToMbFloat(x uint64) float64 {
 }
 
 func ToDouble(x float64) float64 {
-	fmt.Println(x, x*100, math.Round(x*100))
 	return math.Round(x*10000) / 10000
 }
 
@@ -71,7 +70,10 @@ func AlignTicker(ctx context.Context, interval time.Duration, immediate bool) <-
 	return ch
 }
 
-func LogPath(fileName string) string {
-	return "/var/log/" + fileName + ".log"
+func LogPath(dir, fileName string) string {
+	if dir == "" {
+		dir = "/var/log"
+	}
+	return strings.TrimRight(dir, "/") + "/" + fileName + ".log"
 	//return fileName + ".log"
 }